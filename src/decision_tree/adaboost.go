@@ -0,0 +1,133 @@
+package decision_tree
+
+import (
+	"errors"
+	"math"
+)
+
+// AdaBoost ensemble of decision stumps (SAMME, which reduces to AdaBoost.M1 for binary targets). Every stump is grown on
+// the observations reweighted so that the ones misclassified by the previous stumps count more, and votes with the weight
+// reflecting its accuracy.
+type AdaBoost struct {
+	Stumps    []*DecisionTree
+	Alphas    []float64 // Voting weights of the stumps
+	NumRounds int       // Maximal number of boosting rounds (stumps)
+	Options   *Options  // Grow options of the stumps (MaxDepth is always 1); a WeightAttribute and ClassWeights give the initial weights
+}
+
+// Fits the stumps to the observations. Boosting stops early when a stump classifies the weighted observations perfectly,
+// or when it is no better than random guessing.
+func (a *AdaBoost) Fit(observations []*Observation) error {
+	if a.NumRounds <= 0 {
+		return errors.New("Number of boosting rounds must be positive.")
+	}
+	if len(observations) == 0 {
+		return errors.New("Cannot fit on an empty set of observations.")
+	}
+
+	// the current weights of the observations, shared by the stumps through their (internal) grow options
+	weights := make(map[*Observation]float64, len(observations))
+	stumpOptions := *a.Options
+	stumpOptions.MaxDepth, stumpOptions.LeafEstimator, stumpOptions.sampleWeights = 1, nil, weights
+	stumpOptions.ClassWeights, stumpOptions.BalancedClassWeights = nil, false // already part of the initial weights
+
+	root := new(DecisionTree)
+	if err := root.InitRoot(a.Options, observations); err != nil {
+		return err
+	}
+	for _, obs := range observations {
+		weights[obs] = root.weightOf(obs)
+	}
+	if err := normalizeWeights(weights); err != nil {
+		return err
+	}
+
+	classes, err := root.classCounts(root.sampleWeight)
+	if err != nil {
+		return err
+	}
+	K := float64(len(classes.counts))
+
+	a.Stumps, a.Alphas = []*DecisionTree{}, []float64{}
+	for round := 0; round < a.NumRounds; round++ {
+		stump := new(DecisionTree)
+		if err := stump.InitRoot(&stumpOptions, append([]*Observation{}, observations...)); err != nil {
+			return err
+		}
+		if err := stump.Expand(true); err != nil {
+			return err
+		}
+
+		misclassified, weightedError := make([]bool, len(observations)), 0.0
+		for i, obs := range observations {
+			got, err := stump.Classify(obs)
+			if err != nil {
+				return err
+			}
+			if isEq, _ := _eq(got, (*obs)[a.Options.TargetAttribute]); !isEq {
+				misclassified[i] = true
+				weightedError += weights[obs]
+			}
+		}
+		weightedError /= float64(len(observations))
+
+		if weightedError >= 1-1/K { // no better than random guessing
+			if len(a.Stumps) == 0 {
+				a.Stumps, a.Alphas = append(a.Stumps, stump), append(a.Alphas, 1.0)
+			}
+			break
+		}
+		if weightedError <= 1e-12 { // perfect stump, it decides on its own
+			a.Stumps, a.Alphas = append(a.Stumps, stump), append(a.Alphas, math.Log((1-1e-12)/1e-12)+math.Log(K-1))
+			break
+		}
+
+		alpha := math.Log((1-weightedError)/weightedError) + math.Log(K-1)
+		a.Stumps, a.Alphas = append(a.Stumps, stump), append(a.Alphas, alpha)
+		for i, obs := range observations {
+			if misclassified[i] {
+				weights[obs] *= math.Exp(alpha)
+			}
+		}
+		if err := normalizeWeights(weights); err != nil { // the weights overflowed, keep the stumps fitted so far
+			break
+		}
+	}
+	return nil
+}
+
+// Returns the classification for a new observation o: the class with the highest total voting weight of the stumps
+// (ties resolved in favour of the smaller class).
+func (a *AdaBoost) Classify(o *Observation) (Value, error) {
+	if len(a.Stumps) == 0 {
+		return nil, errors.New("The ensemble has not been fitted yet.")
+	}
+
+	votes := newClassCounts(gini)
+	for i, stump := range a.Stumps {
+		got, err := stump.Classify(o)
+		if err != nil {
+			return nil, err
+		}
+		if err := votes.add(got, a.Alphas[i]); err != nil {
+			return nil, err
+		}
+	}
+	return votes.majority(), nil
+}
+
+// Rescales the AdaBoost weights of the observations so that they average to 1 (keeping size-based stop rules meaningful).
+// Returns an error if their total is not a positive, finite number.
+func normalizeWeights(weights map[*Observation]float64) error {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if !(total > 0.0) || math.IsInf(total, 1) {
+		return errors.New("The observations have no weight.")
+	}
+	for obs, weight := range weights {
+		weights[obs] = weight * float64(len(weights)) / total
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package decision_tree
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// An attribute declared in the header of ARFF data.
+type arffAttribute struct {
+	name       string
+	kind       AttributeKind
+	categories []string // Declared values of a nominal attribute
+}
+
+// Reads the ARFF file at the given path (see ReadARFF).
+func ReadARFFFile(path string, targetAttribute string) (*LoadedDataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadARFF(file, targetAttribute)
+}
+
+// Reads observations from ARFF (Weka) data. Numeric (real, integer) attributes are read as float64 values, nominal
+// attributes as string values among the declared ones, string and date attributes as IDs (string values never used
+// as predictors). Unquoted ? values are missing. In sparse rows ({index value, ...}), absent numeric values are zeros
+// and absent nominal values the first declared ones (other absent values are missing). As in Weka, the last attribute
+// is the target unless targetAttribute is given; the target must be numeric or nominal.
+func ReadARFF(r io.Reader, targetAttribute string) (*LoadedDataset, error) {
+	var d *LoadedDataset
+	attributes := []*arffAttribute{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '%' {
+			continue
+		}
+
+		var err error
+		if d != nil {
+			var obs *Observation
+			if obs, err = parseARFFRow(text, attributes, d.TargetAttribute); err == nil {
+				d.Observations = append(d.Observations, obs)
+			}
+		} else if keyword := strings.ToLower(strings.Fields(text)[0]); keyword == "@attribute" {
+			var attribute *arffAttribute
+			if attribute, err = parseARFFAttribute(text[len(keyword):]); err == nil {
+				attributes = append(attributes, attribute)
+			}
+		} else if keyword == "@data" {
+			d, err = newARFFDataset(attributes, targetAttribute)
+		} else if keyword != "@relation" {
+			err = errors.New("unexpected declaration " + keyword + ".")
+		}
+		if err != nil {
+			return nil, errors.New("Line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	} else if d == nil {
+		return nil, errors.New("Missing @data section.")
+	}
+	return d, nil
+}
+
+// Parses the declaration of an attribute following the @attribute keyword.
+func parseARFFAttribute(declaration string) (*arffAttribute, error) {
+	name, rest, err := nextARFFToken(declaration)
+	if err != nil {
+		return nil, err
+	} else if name == "" {
+		return nil, errors.New("missing attribute name.")
+	}
+
+	attribute := &arffAttribute{name: name}
+	rest = strings.TrimSpace(rest)
+	switch kind := strings.ToLower(strings.Fields(rest + " ")[0]); {
+	case strings.HasPrefix(rest, "{") && strings.HasSuffix(rest, "}"):
+		attribute.kind = CategoricalAttribute
+		values, err := splitARFFList(rest[1 : len(rest)-1])
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			category, _, err := unquoteARFF(value)
+			if err != nil {
+				return nil, err
+			}
+			attribute.categories = append(attribute.categories, category)
+		}
+	case kind == "numeric" || kind == "real" || kind == "integer":
+		attribute.kind = NumericAttribute
+	case kind == "string" || kind == "date":
+		attribute.kind = IDAttribute
+	default:
+		return nil, errors.New("unsupported type of attribute " + name + ".")
+	}
+	return attribute, nil
+}
+
+// Returns the dataset declared by the ARFF header, its target being the given attribute (the last one if not given).
+func newARFFDataset(attributes []*arffAttribute, targetAttribute string) (*LoadedDataset, error) {
+	if len(attributes) == 0 {
+		return nil, errors.New("no attributes declared.")
+	} else if targetAttribute == "" {
+		targetAttribute = attributes[len(attributes)-1].name
+	}
+
+	d := &LoadedDataset{TargetAttribute: targetAttribute, Kinds: map[string]AttributeKind{}, NominalValues: map[string][]string{}}
+	for _, attribute := range attributes {
+		if _, ok := d.Kinds[attribute.name]; ok {
+			return nil, errors.New("duplicate attribute " + attribute.name + ".")
+		}
+		d.Kinds[attribute.name] = attribute.kind
+		if attribute.kind == CategoricalAttribute {
+			d.NominalValues[attribute.name] = attribute.categories
+		}
+		if attribute.name != targetAttribute && attribute.kind != IDAttribute {
+			d.Predictors = append(d.Predictors, attribute.name)
+			if attribute.kind == CategoricalAttribute {
+				d.CategoricalPredictors = append(d.CategoricalPredictors, attribute.name)
+			}
+		}
+	}
+
+	if kind, ok := d.Kinds[targetAttribute]; !ok {
+		return nil, errors.New("target attribute " + targetAttribute + " not declared.")
+	} else if kind == IDAttribute {
+		return nil, errors.New("the target attribute " + targetAttribute + " must be numeric or nominal.")
+	}
+	return d, nil
+}
+
+// Parses a dense or sparse data row into an observation.
+func parseARFFRow(row string, attributes []*arffAttribute, target string) (*Observation, error) {
+	values := map[int]string{}
+	if strings.HasPrefix(row, "{") && strings.HasSuffix(row, "}") { // sparse row
+		items, err := splitARFFList(row[1 : len(row)-1])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			index, value, err := nextARFFToken(item)
+			a, err1 := strconv.Atoi(index)
+			if err != nil || err1 != nil || a < 0 || a >= len(attributes) {
+				return nil, errors.New("invalid sparse value \"" + item + "\".")
+			}
+			values[a] = value
+		}
+	} else {
+		items, err := splitARFFList(row)
+		if err != nil {
+			return nil, err
+		} else if len(items) != len(attributes) {
+			return nil, errors.New("expected " + strconv.Itoa(len(attributes)) + " values, got " + strconv.Itoa(len(items)) + ".")
+		}
+		for a, item := range items {
+			values[a] = item
+		}
+	}
+
+	obs := Observation{}
+	for a, attribute := range attributes {
+		raw, ok := values[a]
+		if !ok { // absent from a sparse row
+			if attribute.kind == NumericAttribute {
+				obs[attribute.name] = 0.0
+			} else if attribute.kind == CategoricalAttribute && len(attribute.categories) > 0 {
+				obs[attribute.name] = attribute.categories[0]
+			}
+			continue
+		}
+
+		value, quoted, err := unquoteARFF(raw)
+		if err != nil {
+			return nil, err
+		}
+		if value == "?" && !quoted {
+			continue
+		}
+
+		switch attribute.kind {
+		case NumericAttribute:
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, errors.New("invalid numeric value \"" + value + "\" of attribute " + attribute.name + ".")
+			}
+			obs[attribute.name] = v
+		case CategoricalAttribute:
+			declared := false
+			for _, category := range attribute.categories {
+				declared = declared || category == value
+			}
+			if !declared {
+				return nil, errors.New("undeclared value \"" + value + "\" of attribute " + attribute.name + ".")
+			}
+			obs[attribute.name] = value
+		default:
+			obs[attribute.name] = value
+		}
+	}
+	if isMissing(obs[target]) {
+		return nil, errors.New("missing target value.")
+	}
+	return &obs, nil
+}
+
+// Splits a comma-separated ARFF list into its (possibly quoted) values.
+func splitARFFList(list string) (values []string, err error) {
+	start, quote := 0, byte(0)
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote == 0 && c == ',':
+			values, start = append(values, list[start:i]), i+1
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote.")
+	}
+	return append(values, list[start:]), nil
+}
+
+// Returns the first (possibly quoted) whitespace-delimited token of the text and the rest of the text.
+func nextARFFToken(text string) (token string, rest string, err error) {
+	text = strings.TrimSpace(text)
+	end := strings.IndexAny(text, " \t")
+	if text != "" && (text[0] == '\'' || text[0] == '"') {
+		end = -1
+		for i := 1; i < len(text) && end < 0; i++ {
+			if text[i] == '\\' {
+				i++
+			} else if text[i] == text[0] {
+				end = i + 1
+			}
+		}
+		if end < 0 {
+			return "", "", errors.New("unterminated quote.")
+		}
+	}
+	if end < 0 {
+		end = len(text)
+	}
+	token, _, err = unquoteARFF(text[:end])
+	return token, text[end:], err
+}
+
+// Removes the quotes (and the escapes within them) from an ARFF value.
+// Output: the value and true iff it was quoted.
+func unquoteARFF(value string) (string, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || (value[0] != '\'' && value[0] != '"') {
+		return value, false, nil
+	}
+	if len(value) < 2 || value[len(value)-1] != value[0] {
+		return "", true, errors.New("unterminated quote.")
+	}
+
+	var unquoted strings.Builder
+	for i := 1; i < len(value)-1; i++ {
+		if value[i] == '\\' && i+2 < len(value) {
+			i++
+		}
+		unquoted.WriteByte(value[i])
+	}
+	return unquoted.String(), true, nil
+}
@@ -0,0 +1,91 @@
+package decision_tree
+
+import (
+	"strings"
+	"testing"
+)
+
+const WEATHER_ARFF = `% The weather data of Weka
+@relation weather
+
+@attribute outlook {sunny, overcast, rainy}
+@attribute temperature numeric
+@attribute 'relative humidity' real
+@attribute windy {TRUE, FALSE}
+@attribute day string
+@attribute play {yes, no}
+
+@data
+sunny,85,85,FALSE,mon,no
+sunny,80,90,TRUE,tue,no
+overcast,83,86,FALSE,wed,yes
+rainy,70,96,FALSE,thu,yes
+rainy,68,80,FALSE,fri,yes
+rainy,65,70,TRUE,sat,no
+overcast,64,65,TRUE,sun,yes
+sunny,72,95,FALSE,'mon 2',no
+sunny,69,70,FALSE,tue,yes
+rainy,75,?,FALSE,wed,yes
+sunny,75,70,TRUE,thu,yes
+overcast,72,90,TRUE,fri,yes
+overcast,81,75,FALSE,sat,yes
+rainy,71,91,TRUE,sun,no
+`
+
+func Test_ReadARFF(tst *testing.T) {
+	d, err := ReadARFF(strings.NewReader(WEATHER_ARFF), "")
+	if err != nil {
+		tst.Fatalf("Reading ARFF data failed: %s", err.Error())
+	}
+	if len(d.Observations) != 14 || d.TargetAttribute != "play" || strings.Join(d.Predictors, ",") != "outlook,temperature,relative humidity,windy" ||
+		strings.Join(d.CategoricalPredictors, ",") != "outlook,windy" || strings.Join(d.NominalValues["play"], ",") != "yes,no" {
+		tst.Errorf("ARFF header test failed, got target %s, predictors %v and categorical predictors %v.", d.TargetAttribute, d.Predictors, d.CategoricalPredictors)
+	}
+	if obs := *d.Observations[9]; obs["temperature"] != 75.0 || !isMissing(obs["relative humidity"]) || obs["outlook"] != "rainy" || (*d.Observations[7])["day"] != "mon 2" {
+		tst.Errorf("ARFF data test failed, got %v.", obs)
+	}
+
+	settings := getSettings("supergrow", "")
+	d.Configure(settings)
+	t := new(DecisionTree)
+	t.InitRoot(settings, d.Observations)
+	t.Expand(true)
+	successes := 0
+	for _, obs := range d.Observations {
+		if got, _ := t.Classify(obs); got == (*obs)["play"] {
+			successes++
+		}
+	}
+	if predictor, _, _ := t.GetRule(); predictor != "outlook" || successes < 12 {
+		tst.Errorf("ARFF tree test failed, split on %s and classified %d/14 observations correctly.", predictor, successes)
+	}
+}
+
+func Test_ReadSparseARFF(tst *testing.T) {
+	data := "@RELATION sparse\n@ATTRIBUTE a NUMERIC\n@ATTRIBUTE b {x, y}\n@ATTRIBUTE c NUMERIC\n@DATA\n{0 1.5, 2 3}\n{1 y, 2 ?}\n"
+	d, err := ReadARFF(strings.NewReader(data), "a")
+	if err != nil {
+		tst.Fatalf("Reading sparse ARFF data failed: %s", err.Error())
+	}
+	first, second := *d.Observations[0], *d.Observations[1]
+	if first["a"] != 1.5 || first["b"] != "x" || first["c"] != 3.0 || second["a"] != 0.0 || second["b"] != "y" || !isMissing(second["c"]) {
+		tst.Errorf("Sparse ARFF test failed, got %v and %v.", first, second)
+	}
+
+	cases := map[string]string{
+		"@attribute a numeric\n@attribute b {x}\n@data\n1,z\n":   "Line 4: undeclared value \"z\" of attribute b.",
+		"@attribute a numeric\n@attribute b {x}\n@data\n1\n":     "Line 4: expected 2 values, got 1.",
+		"@attribute a numeric\n@attribute b {x}\n@data\n1,?\n":   "Line 4: missing target value.",
+		"@attribute a numeric\n@attribute b {x}\n@data\nq,x\n":   "Line 4: invalid numeric value \"q\" of attribute a.",
+		"@attribute a relational\n@data\n":                       "Line 1: unsupported type of attribute a.",
+		"@attribute a numeric\n@attribute b string\n@data\n":     "Line 3: the target attribute b must be numeric or nominal.",
+		"@attribute a numeric\n@attribute b {x}\n":               "Missing @data section.",
+		"@attribute a numeric\n@attribute b {x}\n@data\n{5 1}\n": "Line 4: invalid sparse value \"5 1\".",
+		"@attribute 'a numeric\n":                                "Line 1: unterminated quote.",
+	}
+	for data, expected := range cases {
+		if _, err := ReadARFF(strings.NewReader(data), ""); err == nil || err.Error() != expected {
+			tst.Errorf("ARFF error test failed. Expected '%s', got '%v'.", expected, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package decision_tree
+
+import "container/heap"
+
+// A leaf waiting to be split by the best-first growth, together with its best split.
+type expansion struct {
+	node      *DecisionTree
+	predictor string
+	index     int
+	decrease  float64 // Weighted impurity decrease of the split (see impurityDecrease)
+	order     int     // Order of discovery, resolving ties in favour of older leaves
+}
+
+// Max-heap of expansions ordered by the impurity decrease of their splits.
+type expansionQueue []*expansion
+
+func (q expansionQueue) Len() int { return len(q) }
+func (q expansionQueue) Less(i, j int) bool {
+	if q[i].decrease != q[j].decrease {
+		return q[i].decrease > q[j].decrease
+	}
+	return q[i].order < q[j].order
+}
+func (q expansionQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *expansionQueue) Push(x interface{}) { *q = append(*q, x.(*expansion)) }
+func (q *expansionQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// Grows the tree rooted in this node best-first: the leaves are kept in a priority queue ordered by the (weighted) impurity
+// decrease of their best split, and the leaf with the greatest decrease is always split next, until Options.MaxLeafNodes
+// leaves are grown or no leaf can be split any further. Unlike the depth-first Expand, this yields the best tree
+// with a limited number of leaves. All other stop rules of the grow options apply as usual.
+func (t *DecisionTree) ExpandBestFirst() error {
+	queue := &expansionQueue{}
+	order := 0
+	enqueue := func(node *DecisionTree) error {
+		candidate, err := node.findExpansion()
+		if err != nil || candidate == nil {
+			return err
+		}
+		candidate.order, order = order, order+1
+		heap.Push(queue, candidate)
+		return nil
+	}
+
+	if err := enqueue(t); err != nil {
+		return err
+	}
+	for queue.Len() > 0 {
+		if !t.grow.reserveLeaf(t.Options.MaxLeafNodes) {
+			break
+		}
+		best := heap.Pop(queue).(*expansion)
+
+		best.node.Classification = nil
+		if err := best.node.splitNode(best.predictor, best.index); err != nil {
+			return err
+		}
+		for _, child := range []*DecisionTree{best.node.left, best.node.right} {
+			if err := enqueue(child); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, remaining := range *queue {
+		remaining.node.releaseHistograms()
+	}
+	return nil
+}
+
+// Prepares this node to become a leaf and finds its best split. Every node is given its leaf value, so that the leaves
+// remaining in the queue when the growth stops are complete.
+// Output: the expansion of the node, or nil if the node cannot be split.
+func (t *DecisionTree) findExpansion() (*expansion, error) {
+	if err := t.rememberClassCounts(); err != nil {
+		return nil, err
+	}
+	leafValue, err := t.getLeafValue()
+	if err != nil {
+		return nil, err
+	}
+	t.Classification = leafValue
+
+	if canGrow, err := t.isGrowable(); err != nil || !canGrow {
+		t.releaseHistograms()
+		return nil, err
+	}
+	predictor, index, purity, err := t.FindBestSplit()
+	if err != nil || predictor == NO_PREDICTOR {
+		t.releaseHistograms()
+		return nil, err
+	}
+	decrease, err := t.impurityDecrease(*purity)
+	if err != nil {
+		return nil, err
+	}
+	return &expansion{node: t, predictor: predictor, index: *index, decrease: decrease}, nil
+}
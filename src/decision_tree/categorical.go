@@ -0,0 +1,92 @@
+package decision_tree
+
+import (
+	"sort"
+	"strings"
+)
+
+// A set of categories of a categorical predictor. Observations whose predictor value belongs to the set
+// continue to the left subtree, all other observations (including unseen categories) to the right subtree.
+type CategorySet []Value
+
+// Returns true iff the given value is one of the categories in the set.
+func (c CategorySet) Contains(v Value) bool {
+	for _, category := range c {
+		if isEq, err := _eq(category, v); err == nil && isEq {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the set formatted as {A,C,F}.
+func (c CategorySet) String() string {
+	categories := make([]string, len(c))
+	for i, category := range c {
+		categories[i], _ = _str(category)
+	}
+	return "{" + strings.Join(categories, ",") + "}"
+}
+
+// Returns true iff the given predictor was declared categorical in the grow options.
+func (t *DecisionTree) isCategorical(predictor string) bool {
+	if t.Options.CategoricalPredictors == nil {
+		return false
+	}
+	for _, p := range *t.Options.CategoricalPredictors {
+		if predictor == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Orders the categories of the predictor within this node by the rate of the node's majority class (by the mean target value
+// for regression trees). Splitting the categories ordered this way only at the ends of runs yields the optimal subset
+// partition for binary targets and regression (Breiman et al.); for 3+ classes it is a heuristic.
+// Output: a map from each category to its rank.
+func (t *DecisionTree) categoryRanks(predictor string) map[Value]float64 {
+	sums, counts := map[Value]float64{}, map[Value]float64{}
+
+	switch t.Options.SplitStrategy.(type) {
+	case MSEPurity, MAEPurity:
+		for _, obs := range t.Observations {
+			category := (*obs)[predictor]
+			y, _ := _float((*obs)[t.Options.TargetAttribute])
+			sums[category] += t.weightOf(obs) * y
+			counts[category] += t.weightOf(obs)
+		}
+	default:
+		reference, _ := t.getMajorityVote()
+		for _, obs := range t.Observations {
+			category := (*obs)[predictor]
+			if isEq, _ := _eq(reference, (*obs)[t.Options.TargetAttribute]); isEq {
+				sums[category] += t.weightOf(obs)
+			}
+			counts[category] += t.weightOf(obs)
+		}
+	}
+
+	ranks := map[Value]float64{}
+	for category, count := range counts {
+		if count > 0.0 {
+			ranks[category] = sums[category] / count
+		}
+	}
+	return ranks
+}
+
+// Returns the categories of the predictor present in the given observations, sorted.
+func categoriesOf(observations []*Observation, predictor string) CategorySet {
+	seen := map[Value]bool{}
+	categories := CategorySet{}
+	for _, obs := range observations {
+		category := (*obs)[predictor]
+		if !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool { return _before(categories[i], categories[j]) })
+	return categories
+}
@@ -0,0 +1,98 @@
+package decision_tree
+
+// Misclassification costs: LossMatrix[actual][predicted] is the cost of predicting the class `predicted` for an observation
+// of the class `actual`. Missing entries cost 0 for correct predictions and 1 otherwise, so an empty matrix is the 0/1 loss.
+// Note that classes are looked up as map keys, so they must have the same type as the target values (e.g. float64).
+type LossMatrix map[Value]map[Value]float64
+
+// Returns the cost of predicting the class `predicted` for an observation of the class `actual`.
+func (l LossMatrix) cost(actual Value, predicted Value) float64 {
+	if cost, ok := l[actual][predicted]; ok {
+		return cost
+	}
+	if isEq, _ := _eq(actual, predicted); isEq {
+		return 0.0
+	}
+	return 1.0
+}
+
+// Returns the cost-weighted gini impurity of the class distribution described by the counts: the expected cost
+// of labelling an observation drawn from the distribution by a class drawn from the same distribution,
+// sum over i, j of cost(i, j) * p(i) * p(j). Without explicit costs, this is the ordinary gini impurity.
+func (l LossMatrix) gini(counts map[Value]float64, total float64) (impurity float64) {
+	for actual, actualCount := range counts {
+		for predicted, predictedCount := range counts {
+			impurity += l.cost(actual, predicted) * (actualCount / total) * (predictedCount / total)
+		}
+	}
+	return
+}
+
+// Implemented by purity metrics taking the misclassification costs (Options.LossMatrix) into account.
+type costSensitivePurityMetric interface {
+	newCostAccumulator(losses LossMatrix) targetAccumulator
+}
+
+// Returns a new accumulator of the metric for this tree, aware of the misclassification costs if the metric supports them.
+func (t *DecisionTree) newAccumulator(m scanningPurityMetric) targetAccumulator {
+	if metric, ok := m.(costSensitivePurityMetric); ok && t.Options.LossMatrix != nil {
+		return metric.newCostAccumulator(t.Options.LossMatrix)
+	}
+	return m.newAccumulator()
+}
+
+// Returns the class minimizing the expected cost of misclassification of the counted observations (ties resolved
+// in favour of the smaller class). Every class counted or mentioned in the loss matrix is a candidate.
+func (c *classCounts) cheapest(losses LossMatrix) (best Value) {
+	candidates := map[Value]float64{}
+	for class := range c.counts {
+		candidates[class] = 0.0
+	}
+	for actual, row := range losses {
+		candidates[actual] = 0.0
+		for predicted := range row {
+			candidates[predicted] = 0.0
+		}
+	}
+
+	bestCost := 0.0
+	for _, predicted := range sortedClasses(candidates) {
+		cost := 0.0
+		for actual, count := range c.counts {
+			cost += count * losses.cost(actual, predicted)
+		}
+		if best == nil || cost < bestCost {
+			best, bestCost = predicted, cost
+		}
+	}
+	return
+}
+
+// Resolves the weights of the target classes of the tree rooted in this node: Options.ClassWeights, or with
+// Options.BalancedClassWeights the weights N / (K * N_c) making all K classes of the root observations equally heavy
+// (N_c being the total sample weight of the class c). Regression trees have no class weights.
+func (t *DecisionTree) resolveClassWeights() (map[Value]float64, error) {
+	if t.Options.LeafEstimator != nil || (t.Options.ClassWeights == nil && !t.Options.BalancedClassWeights) {
+		return nil, nil
+	}
+	if !t.Options.BalancedClassWeights {
+		return t.Options.ClassWeights, nil
+	}
+
+	counts, err := t.classCounts(t.sampleWeight)
+	if err != nil {
+		return nil, err
+	}
+	return balancedClassWeights(counts), nil
+}
+
+// Returns the weights N / (K * N_c) of the K classes making them equally heavy (see Options.BalancedClassWeights).
+func balancedClassWeights(counts *classCounts) map[Value]float64 {
+	weights, K := map[Value]float64{}, float64(len(counts.counts))
+	for class, count := range counts.counts {
+		if count > 0.0 {
+			weights[class] = counts.total / (K * count)
+		}
+	}
+	return weights
+}
@@ -0,0 +1,456 @@
+package decision_tree
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Working state of growing a tree from a Dataset (see GrowDataset).
+type columnarGrowth struct {
+	dataset    *Dataset
+	metric     scanningPurityMetric
+	predictors map[string]int // Position of every predictor among the columns of the dataset
+	keys       [][]float64    // Ordering keys of the values of every column (strings are ranked lexicographically)
+	ordered    [][]int        // Rows sorted by the keys of every ordered column (nil for categorical columns), missing values last
+	rows       []int          // Rows in no particular order; every node owns the same segment of rows and of every ordered column
+	targets    []Value        // Target values of the rows
+	weights    []float64      // Weights of the rows, including the class weights (see weightOf)
+	sizes      []float64      // Sample weights of the rows, measuring the sizes of nodes (see sampleWeight)
+	goesLeft   []bool         // Marks the rows sent to the left child of the node being split
+	buffer     []int          // Scratch space of the partitions
+}
+
+// The best split of a node found by the columnar growth.
+type columnarSplit struct {
+	predictor  string
+	index      int              // Number of present rows (in the order of the ordered column) sent to the left
+	categories map[float64]bool // Keys of the categories sent to the left (categorical predictors)
+	values     CategorySet      // The categories sent to the left
+	purity     float64
+}
+
+// Grows the tree on the columnar dataset, like InitRoot followed by Expand(true) on the observations of the dataset.
+// Instead of sorting the observations in every node, the rows are sorted by every ordered predictor once and the sorted
+// orders are partitioned along with the splits. Predictors listed in Options.CategoricalPredictors are split by category
+// subsets, all other columns (string columns ordered lexicographically) by thresholds.
+// Columnar growth supports the split strategies evaluated by accumulators (all built-in ones but MAEPurity); it learns
+// no surrogate splits, ignores the histogram and concurrency options, and the grown nodes keep no observations
+// (so the tree cannot be pruned).
+func (t *DecisionTree) GrowDataset(growOptions *Options, d *Dataset) error {
+	if d.Len() == 0 {
+		return nil
+	}
+	metric, ok := growOptions.SplitStrategy.(scanningPurityMetric)
+	if !ok {
+		return errors.New("The split strategy is not supported by columnar growth.")
+	}
+	if growOptions.MaxSurrogates > 0 {
+		return errors.New("Surrogate splits are not supported by columnar growth.")
+	}
+	if d.Target.Name != growOptions.TargetAttribute {
+		return errors.New("The target column does not match the target attribute.")
+	}
+	if err := d.check(); err != nil {
+		return err
+	}
+
+	t.initNode(growOptions, 0)
+	t.seed = growOptions.Seed
+	t.grow = &growState{leaves: 1}
+	g, err := t.newColumnarGrowth(d, metric)
+	if err != nil {
+		return err
+	}
+	return g.grow(t, 0, d.Len())
+}
+
+// Prepares the columnar growth of the tree rooted in this node: encodes the columns, resolves the weights of the rows
+// and sorts the rows by every ordered predictor.
+func (t *DecisionTree) newColumnarGrowth(d *Dataset, metric scanningPurityMetric) (*columnarGrowth, error) {
+	n := d.Len()
+	g := &columnarGrowth{dataset: d, metric: metric, predictors: map[string]int{}, targets: make([]Value, n),
+		weights: make([]float64, n), sizes: make([]float64, n), goesLeft: make([]bool, n), buffer: make([]int, n)}
+
+	for _, predictor := range *t.Options.Predictors {
+		if d.Column(predictor) == nil {
+			return nil, errors.New("Column " + predictor + " not found in the dataset.")
+		}
+	}
+	for p, column := range d.Columns {
+		g.predictors[column.Name] = p
+		g.keys = append(g.keys, columnKeys(column))
+	}
+
+	plain := newClassCounts(gini)
+	for row := range g.targets {
+		g.targets[row], g.weights[row] = d.Target.Value(row), 1.0
+		if err := _check(g.targets[row]); err != nil {
+			return nil, err
+		}
+		if d.Weights != nil {
+			if g.weights[row] = d.Weights.Floats[row]; g.weights[row] < 0.0 || math.IsNaN(g.weights[row]) {
+				return nil, errors.New("Weights must not be negative.")
+			}
+		}
+		g.sizes[row] = g.weights[row]
+		plain.add(g.targets[row], g.weights[row])
+	}
+
+	// resolve the class weights (see resolveClassWeights)
+	if t.Options.LeafEstimator == nil && t.Options.BalancedClassWeights {
+		t.grow.classWeights = balancedClassWeights(plain)
+	} else if t.Options.LeafEstimator == nil {
+		t.grow.classWeights = t.Options.ClassWeights
+	}
+	for row, target := range g.targets {
+		if classWeight, ok := t.grow.classWeights[target]; ok {
+			g.weights[row] *= classWeight
+		}
+		t.grow.rootWeight += g.weights[row]
+	}
+
+	g.rows = make([]int, n)
+	for row := range g.rows {
+		g.rows[row] = row
+	}
+	g.ordered = make([][]int, len(d.Columns))
+	for p, column := range d.Columns {
+		if t.isCategorical(column.Name) {
+			continue
+		}
+		ordered, keys := append([]int{}, g.rows...), g.keys[p]
+		sort.Slice(ordered, func(i, j int) bool {
+			if column.IsMissing(ordered[i]) || column.IsMissing(ordered[j]) { // missing values come last
+				return !column.IsMissing(ordered[i])
+			}
+			return keys[ordered[i]] < keys[ordered[j]]
+		})
+		g.ordered[p] = ordered
+	}
+	return g, nil
+}
+
+// Returns the ordering keys of the values of the column: the numeric values, or the lexicographic ranks of the strings.
+func columnKeys(column *Column) []float64 {
+	switch column.Type {
+	case IntColumn:
+		keys := make([]float64, len(column.Ints))
+		for row, v := range column.Ints {
+			keys[row] = float64(v)
+		}
+		return keys
+	case StringColumn:
+		distinct := map[string]float64{}
+		for _, s := range column.Strings {
+			distinct[s] = 0.0
+		}
+		sorted := make([]string, 0, len(distinct))
+		for s := range distinct {
+			sorted = append(sorted, s)
+		}
+		sort.Strings(sorted)
+		for rank, s := range sorted {
+			distinct[s] = float64(rank)
+		}
+		keys := make([]float64, len(column.Strings))
+		for row, s := range column.Strings {
+			keys[row] = distinct[s]
+		}
+		return keys
+	}
+	return column.Floats
+}
+
+// Grows the node owning the rows of the segment [lo, hi), mirroring Expand(true).
+func (g *columnarGrowth) grow(t *DecisionTree, lo int, hi int) error {
+	acc := g.accumulate(t.newAccumulator(g.metric), g.rows[lo:hi], g.weights)
+	impurity := acc.impurity()
+	t.impurity = &impurity
+	size := 0.0
+	for _, row := range g.rows[lo:hi] {
+		size += g.sizes[row]
+	}
+
+	// Remember the class distribution (of the sample weights) for probability estimates
+	var counts *classCounts
+	if t.Options.LeafEstimator == nil {
+		if counts, _ = acc.(*classCounts); counts == nil {
+			counts = g.accumulate(newClassCounts(gini), g.rows[lo:hi], g.weights).(*classCounts)
+		}
+		t.ClassCounts = g.accumulate(newClassCounts(gini), g.rows[lo:hi], g.sizes).(*classCounts).counts
+	}
+
+	if !t.isGrowableWith(size) {
+		return g.makeLeaf(t, counts, lo, hi)
+	}
+	split := g.findBestSplit(t, counts, lo, hi, acc.weight())
+	if split != nil && t.Options.MinImpurityDecrease > 0.0 && t.weightedDecrease(acc.weight(), impurity-split.purity) < t.Options.MinImpurityDecrease {
+		split = nil
+	}
+	if split == nil || !t.grow.reserveLeaf(t.Options.MaxLeafNodes) {
+		return g.makeLeaf(t, counts, lo, hi)
+	}
+
+	mid := g.split(t, split, lo, hi)
+	t.setLeft(new(DecisionTree))
+	t.setRight(new(DecisionTree))
+	if err := g.grow(t.left, lo, mid); err != nil {
+		return err
+	}
+	return g.grow(t.right, mid, hi)
+}
+
+// Returns the accumulator with the target values of the rows added, counting with the given weights of the rows.
+func (g *columnarGrowth) accumulate(acc targetAccumulator, rows []int, weights []float64) targetAccumulator {
+	for _, row := range rows {
+		acc.add(g.targets[row], weights[row]) // the targets are checked beforehand
+	}
+	return acc
+}
+
+// Turns the node into a leaf predicting the leaf value of its rows (see getLeafValue).
+func (g *columnarGrowth) makeLeaf(t *DecisionTree, counts *classCounts, lo int, hi int) (err error) {
+	if counts != nil {
+		if t.Options.LossMatrix != nil {
+			t.Classification = counts.cheapest(t.Options.LossMatrix)
+		} else {
+			t.Classification = counts.majority()
+		}
+		return nil
+	}
+
+	// leaf estimators work with observations holding the target values
+	observations, weights := make([]*Observation, hi-lo), map[*Observation]float64{}
+	for i, row := range g.rows[lo:hi] {
+		observations[i] = &Observation{t.Options.TargetAttribute: g.targets[row]}
+		weights[observations[i]] = g.weights[row]
+	}
+	if estimator, ok := t.Options.LeafEstimator.(weightedLeafEstimator); ok {
+		t.Classification, err = estimator.estimateWeighted(observations, t.Options.TargetAttribute, func(o *Observation) float64 { return weights[o] })
+	} else {
+		t.Classification, err = t.Options.LeafEstimator.Estimate(observations, t.Options.TargetAttribute)
+	}
+	return err
+}
+
+// Finds the best split of the node owning the rows of the segment [lo, hi) among its candidate predictors, blending the purity
+// of splits with the node impurity for rows missing the predictor (see bestSplitWithPredictor).
+// Output: the best split, or nil if there is none.
+func (g *columnarGrowth) findBestSplit(t *DecisionTree, counts *classCounts, lo int, hi int, weight float64) (best *columnarSplit) {
+	for _, predictor := range t.candidatePredictors() {
+		p := g.predictors[predictor]
+		var split *columnarSplit
+		var presentWeight float64
+		if g.ordered[p] == nil {
+			split, presentWeight = g.categoricalSplit(t, counts, p, g.rows[lo:hi])
+		} else {
+			split, presentWeight = g.orderedSplit(t, p, g.ordered[p][lo:hi])
+		}
+		if split == nil {
+			continue
+		}
+
+		split.predictor = predictor
+		if presentWeight < weight {
+			fraction := presentWeight / weight
+			split.purity = fraction*split.purity + (1-fraction)*(*t.impurity)
+		}
+		if best == nil || split.purity < best.purity {
+			best = split
+		}
+	}
+	return
+}
+
+// Finds the best threshold on the ordered column p for the rows (sorted by the column, see scanSplits).
+// Output: the best split (or nil) and the total weight of the rows having the predictor.
+func (g *columnarGrowth) orderedSplit(t *DecisionTree, p int, rows []int) (*columnarSplit, float64) {
+	column, keys := g.dataset.Columns[p], g.keys[p]
+	present := len(rows)
+	for present > 0 && column.IsMissing(rows[present-1]) {
+		present--
+	}
+
+	left, right := t.newAccumulator(g.metric), g.accumulate(t.newAccumulator(g.metric), rows[:present], g.weights)
+	presentWeight := right.weight()
+	leftSize, rightSize := 0.0, 0.0
+	for _, row := range rows[:present] {
+		rightSize += g.sizes[row]
+	}
+
+	var best *columnarSplit
+	for i := 1; i < present; i++ {
+		moved := rows[i-1]
+		left.add(g.targets[moved], g.weights[moved])
+		right.remove(g.targets[moved], g.weights[moved])
+		leftSize, rightSize = leftSize+g.sizes[moved], rightSize-g.sizes[moved]
+		if keys[moved] == keys[rows[i]] || !t.isLargeEnough(leftSize, rightSize) {
+			continue
+		}
+
+		purity := g.metric.splitPurity(left, right)
+		if best == nil || purity < best.purity {
+			best = &columnarSplit{index: i, purity: purity}
+		}
+	}
+	return best, presentWeight
+}
+
+// Statistics of the rows of one category of a categorical column.
+type categoryAccumulator struct {
+	key   float64
+	value Value
+	rank  float64
+	acc   targetAccumulator
+	size  float64 // Total sample weight of the rows
+}
+
+// Finds the best subset of the categories of the column p sent to the left, scanning the categories ordered by their
+// rank (see categoryRanks).
+// Output: the best split (or nil) and the total weight of the rows having the predictor.
+func (g *columnarGrowth) categoricalSplit(t *DecisionTree, counts *classCounts, p int, rows []int) (*columnarSplit, float64) {
+	column, keys := g.dataset.Columns[p], g.keys[p]
+	byKey := map[float64]*categoryAccumulator{}
+	right := t.newAccumulator(g.metric)
+	for _, row := range rows {
+		if column.IsMissing(row) {
+			continue
+		}
+		category, ok := byKey[keys[row]]
+		if !ok {
+			category = &categoryAccumulator{key: keys[row], value: column.Value(row), acc: t.newAccumulator(g.metric)}
+			byKey[keys[row]] = category
+		}
+		category.acc.add(g.targets[row], g.weights[row])
+		category.size += g.sizes[row]
+		right.add(g.targets[row], g.weights[row])
+	}
+	presentWeight := right.weight()
+
+	var reference Value
+	if counts != nil {
+		if t.Options.LossMatrix != nil {
+			reference = counts.cheapest(t.Options.LossMatrix)
+		} else {
+			reference = counts.majority()
+		}
+	}
+	categories := make([]*categoryAccumulator, 0, len(byKey))
+	for _, category := range byKey {
+		if m, ok := category.acc.(*moments); ok && m.w > 0.0 {
+			category.rank = m.sum / m.w
+		} else if c, ok := category.acc.(*classCounts); ok && c.total > 0.0 {
+			category.rank = c.counts[reference] / c.total
+		}
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].rank != categories[j].rank {
+			return categories[i].rank < categories[j].rank
+		}
+		return _before(categories[i].value, categories[j].value)
+	})
+
+	left := t.newAccumulator(g.metric)
+	leftSize, rightSize := 0.0, 0.0
+	for _, category := range categories {
+		rightSize += category.size
+	}
+	var best *columnarSplit
+	for i := 1; i < len(categories); i++ {
+		left.merge(categories[i-1].acc, 1.0)
+		right.merge(categories[i-1].acc, -1.0)
+		leftSize, rightSize = leftSize+categories[i-1].size, rightSize-categories[i-1].size
+		if !t.isLargeEnough(leftSize, rightSize) {
+			continue
+		}
+
+		purity := g.metric.splitPurity(left, right)
+		if best == nil || purity < best.purity {
+			best = &columnarSplit{index: i, purity: purity}
+		}
+	}
+	if best == nil {
+		return nil, presentWeight
+	}
+
+	best.categories = map[float64]bool{}
+	for _, category := range categories[:best.index] {
+		best.categories[category.key] = true
+		best.values = append(best.values, category.value)
+	}
+	sort.Slice(best.values, func(i, j int) bool { return _before(best.values[i], best.values[j]) })
+	return best, presentWeight
+}
+
+// Splits the node owning the rows of the segment [lo, hi): remembers the split information and partitions the segments
+// of the rows and of all ordered columns so that the rows sent to the left come first.
+// Output: the end of the segment of the left child.
+func (g *columnarGrowth) split(t *DecisionTree, split *columnarSplit, lo int, hi int) int {
+	p := g.predictors[split.predictor]
+	column := g.dataset.Columns[p]
+	t.SplitPredictor = &split.predictor
+
+	weightLeft, weightRight := 0.0, 0.0
+	if g.ordered[p] == nil {
+		t.SplitValue = split.values
+		for _, row := range g.rows[lo:hi] {
+			if !column.IsMissing(row) {
+				g.goesLeft[row] = split.categories[g.keys[p][row]]
+				if g.goesLeft[row] {
+					weightLeft += g.weights[row]
+				} else {
+					weightRight += g.weights[row]
+				}
+			}
+		}
+	} else {
+		rows := g.ordered[p][lo:hi]
+		t.SplitValue = column.Value(rows[split.index])
+		for i, row := range rows {
+			if !column.IsMissing(row) {
+				g.goesLeft[row] = i < split.index
+				if g.goesLeft[row] {
+					weightLeft += g.weights[row]
+				} else {
+					weightRight += g.weights[row]
+				}
+			}
+		}
+	}
+
+	// Route rows missing the predictor by the majority direction
+	t.MissingGoesLeft = weightLeft >= weightRight
+	for _, row := range g.rows[lo:hi] {
+		if column.IsMissing(row) {
+			g.goesLeft[row] = t.MissingGoesLeft
+		}
+	}
+
+	mid := g.partition(g.rows[lo:hi])
+	for _, ordered := range g.ordered {
+		if ordered != nil {
+			g.partition(ordered[lo:hi])
+		}
+	}
+	return lo + mid
+}
+
+// Moves the rows going left before the other ones, keeping the order of the rows on both sides.
+// Output: the number of rows going left.
+func (g *columnarGrowth) partition(rows []int) int {
+	left, right := 0, 0
+	buffer := g.buffer[:len(rows)]
+	for _, row := range rows {
+		if g.goesLeft[row] {
+			rows[left] = row
+			left++
+		} else {
+			buffer[right] = row
+			right++
+		}
+	}
+	copy(rows[left:], buffer[:right])
+	return left
+}
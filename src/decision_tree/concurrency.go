@@ -0,0 +1,156 @@
+package decision_tree
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// The best split of a node on one predictor, as found by one of the split workers.
+type predictorSplit struct {
+	index  *int
+	purity *float64
+	err    error
+	order  []*Observation // Observations of the node in the order the split index refers to (kept for the best splits only)
+}
+
+// Finds the best split of this node among the given predictors on a pool of Options.SplitWorkers goroutines. Every worker
+// evaluates its predictors on a private copy of the node, sorting its own view of the observations, so the results
+// are exactly those of the sequential search: ties are resolved in favour of the predictor listed first and the first
+// error (in the order of the predictors) is returned.
+// Side-effects: the observations of the node are sorted by the best predictor.
+func (t *DecisionTree) findBestSplitConcurrently(predictors []string) (bestPredictor string, bestIndex *int, bestPurity *float64, err error) {
+	if _, err := t.Impurity(); err != nil { // memoize before the node is shared among the workers
+		return NO_PREDICTOR, nil, nil, err
+	}
+
+	results := make([]predictorSplit, len(predictors))
+	jobs := make(chan int)
+	workers := t.Options.SplitWorkers
+	if workers > len(predictors) {
+		workers = len(predictors)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			view := *t
+			view.Observations, view._sortedBy = append([]*Observation{}, t.Observations...), nil
+
+			best := -1 // the best predictor evaluated by this worker
+			for i := range jobs {
+				result := &results[i]
+				result.index, result.purity, result.err = view.bestSplitWithPredictor(predictors[i])
+				if result.purity == nil {
+					continue
+				}
+				if best < 0 || *result.purity < *results[best].purity || (*result.purity == *results[best].purity && i < best) {
+					if best >= 0 {
+						results[best].order = nil
+					}
+					result.order, best = append([]*Observation{}, view.Observations...), i
+				}
+			}
+		}()
+	}
+	for i := range predictors {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	bestPredictor, winner := NO_PREDICTOR, -1
+	for i, result := range results {
+		if result.err != nil {
+			return NO_PREDICTOR, nil, nil, result.err
+		}
+		if (bestPurity == nil && result.purity != nil) || (result.purity != nil && *result.purity < *bestPurity) {
+			bestPurity, bestIndex, bestPredictor, winner = result.purity, result.index, predictors[i], i
+		}
+	}
+
+	if winner >= 0 {
+		copy(t.Observations, results[winner].order)
+		t._sortedBy = &predictors[winner]
+	}
+	return
+}
+
+// Coordinates the goroutines growing the subtrees of one tree (see ExpandContext).
+type concurrentGrowth struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	slots  chan struct{} // One token per goroutine growing a subtree besides the calling one
+	once   sync.Once
+	err    error // The first error met while growing the tree
+}
+
+// Records the error unless another one has been recorded before, and stops the growth of all other subtrees.
+func (g *concurrentGrowth) fail(err error) {
+	g.once.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
+
+// Grows the tree like Expand(true), but the subtrees of every split are grown concurrently, using at most
+// Options.ExpandWorkers goroutines (runtime.NumCPU() if not set) at any time. The growth stops as soon as any subtree
+// fails or the context is cancelled (or times out); the first error met (or the error of the context) is returned,
+// leaving the tree partially grown. Cancellation is checked before expanding every node; the nodes left unexpanded
+// become leaves (unless their leaf values cannot be found, see getLeafValue), so that a partially grown tree still classifies.
+func (t *DecisionTree) ExpandContext(ctx context.Context) error {
+	workers := t.Options.ExpandWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	g := &concurrentGrowth{slots: make(chan struct{}, workers-1)}
+	g.ctx, g.cancel = context.WithCancel(ctx)
+	defer g.cancel()
+
+	t.expandConcurrently(g)
+	return g.err
+}
+
+// Expands this node and grows its subtrees, the left one on a new goroutine if a slot is free.
+func (t *DecisionTree) expandConcurrently(g *concurrentGrowth) {
+	if err := g.ctx.Err(); err != nil {
+		g.fail(err)
+		t.stopGrowing()
+		return
+	}
+	split, err := t.expandNode(true)
+	if err != nil {
+		g.fail(err)
+		t.stopGrowing()
+		return
+	}
+	if !split {
+		return
+	}
+
+	select {
+	case g.slots <- struct{}{}:
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-g.slots }()
+			t.left.expandConcurrently(g)
+		}()
+		t.right.expandConcurrently(g)
+		wg.Wait()
+	default:
+		t.left.expandConcurrently(g)
+		t.right.expandConcurrently(g)
+	}
+}
+
+// Turns this node, left unexpanded by a stopped growth, into a leaf. The errors met are ignored, as the growth has failed already.
+func (t *DecisionTree) stopGrowing() {
+	t.rememberClassCounts()
+	t.collapse()
+	t.releaseHistograms()
+}
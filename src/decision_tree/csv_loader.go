@@ -0,0 +1,248 @@
+package decision_tree
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Kinds of the attributes read from a dataset file.
+type AttributeKind int
+
+const (
+	NumericAttribute     AttributeKind = iota // Numeric attribute, read as float64 values
+	CategoricalAttribute                      // Categorical attribute, read as string values
+	IDAttribute                               // Identifier of the rows, read as string values and never used as a predictor
+)
+
+// Explicit description of the columns of a CSV file. The kinds of the columns left out are inferred (see ReadCSV).
+type CSVSchema struct {
+	TargetAttribute string                   // Name of the target column
+	Kinds           map[string]AttributeKind // Kinds of the columns, overriding the inferred ones
+}
+
+// Observations read from a dataset file, together with the attributes usable as predictors.
+type LoadedDataset struct {
+	Observations          []*Observation
+	TargetAttribute       string
+	Predictors            []string                 // Numeric and categorical attributes other than the target, in the order of the file
+	CategoricalPredictors []string                 // The categorical predictors
+	Kinds                 map[string]AttributeKind // Kinds of all attributes, including the target
+	NominalValues         map[string][]string      // Declared values of the categorical attributes (if declared by the file)
+}
+
+// Sets the target attribute, the predictors and the categorical predictors of the grow options to those of the dataset.
+// The weight attribute of the grow options (if set) is left out of the predictors.
+func (d *LoadedDataset) Configure(options *Options) {
+	configure(options, d.TargetAttribute, d.Predictors, d.CategoricalPredictors)
+}
+
+// Sets the target attribute, the predictors and the categorical predictors of the grow options to copies of the given ones,
+// leaving out the weight attribute of the grow options.
+func configure(options *Options, target string, predictors []string, categorical []string) {
+	without := func(attributes []string) []string {
+		kept := []string{}
+		for _, attribute := range attributes {
+			if attribute != options.WeightAttribute {
+				kept = append(kept, attribute)
+			}
+		}
+		return kept
+	}
+	predictors, categorical = without(predictors), without(categorical)
+	options.TargetAttribute, options.Predictors, options.CategoricalPredictors = target, &predictors, &categorical
+}
+
+// Reads the CSV file at the given path (see ReadCSV).
+func ReadCSVFile(path string, schema *CSVSchema) (*LoadedDataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadCSV(file, schema)
+}
+
+// Reads observations from CSV data whose first row holds the attribute names. Empty cells are missing values (left out
+// of the observations); the target value is mandatory. Columns without a name are skipped if they hold no values.
+// Unless given by the schema, the kind of every column is inferred from its name and values: columns named #... or __...
+// and columns without values are IDs, columns whose values all parse as numbers are numeric, columns mixing numbers and other values are
+// categorical, other columns are IDs if their values are all distinct and categorical otherwise. The target is numeric
+// or categorical (whatever its name), never an ID. Malformed rows and values are reported with their line and column.
+func ReadCSV(r io.Reader, schema *CSVSchema) (*LoadedDataset, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("Missing CSV header.")
+	} else if err != nil {
+		return nil, err
+	}
+
+	records, lines := [][]string{}, []int{}
+	inferences := make([]kindInference, len(header))
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		records, lines = append(records, record), append(lines, line)
+		for c, cell := range record {
+			inferences[c].add(cell)
+		}
+	}
+
+	layout, err := newCSVLayout(header, schema, func(c int) AttributeKind { return inferences[c].kind() })
+	if err != nil {
+		return nil, err
+	}
+	d := &LoadedDataset{TargetAttribute: layout.target, Predictors: layout.predictors, CategoricalPredictors: layout.categorical, Kinds: layout.kinds}
+	for i, record := range records {
+		obs, err := layout.parseRecord(record)
+		if err != nil {
+			return nil, errors.New("Line " + strconv.Itoa(lines[i]) + ", " + err.Error())
+		}
+		d.Observations = append(d.Observations, obs)
+	}
+	return d, nil
+}
+
+// Layout of the columns of a CSV file.
+type csvLayout struct {
+	names       []string // Trimmed names of the columns in the order of the file, "" for the unnamed columns to skip
+	target      string
+	kinds       map[string]AttributeKind
+	predictors  []string
+	categorical []string
+}
+
+// Resolves the names and kinds of the columns of the CSV header, the kinds left out of the schema being given by their
+// names or by the infer function (see ReadCSV), and lists the predictors.
+func newCSVLayout(header []string, schema *CSVSchema, infer func(c int) AttributeKind) (*csvLayout, error) {
+	if schema == nil || schema.TargetAttribute == "" {
+		return nil, errors.New("A schema with a target attribute is required.")
+	}
+	l := &csvLayout{names: make([]string, len(header)), target: schema.TargetAttribute, kinds: map[string]AttributeKind{}}
+	for c, name := range header {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		} else if _, ok := l.kinds[name]; ok {
+			return nil, errors.New("Duplicate column " + name + ".")
+		}
+		l.names[c] = name
+
+		kind, ok := schema.Kinds[name]
+		switch {
+		case ok && name == l.target && kind == IDAttribute:
+			return nil, errors.New("The target column " + name + " cannot be an ID.")
+		case ok:
+		case name == l.target:
+			if kind = infer(c); kind == IDAttribute {
+				kind = CategoricalAttribute
+			}
+		case strings.HasPrefix(name, "#") || strings.HasPrefix(name, "__"):
+			kind = IDAttribute
+		default:
+			kind = infer(c)
+		}
+		l.kinds[name] = kind
+
+		if name != l.target && kind != IDAttribute {
+			l.predictors = append(l.predictors, name)
+			if kind == CategoricalAttribute {
+				l.categorical = append(l.categorical, name)
+			}
+		}
+	}
+
+	if _, ok := l.kinds[l.target]; !ok {
+		return nil, errors.New("Target column " + l.target + " not found.")
+	}
+	for name := range schema.Kinds {
+		if _, ok := l.kinds[name]; !ok {
+			return nil, errors.New("Column " + name + " of the schema not found.")
+		}
+	}
+	return l, nil
+}
+
+// Parses the values of one record into an observation.
+func (l *csvLayout) parseRecord(record []string) (*Observation, error) {
+	obs := Observation{}
+	for c, name := range l.names {
+		if cell, err := l.parseCell(record, c); err != nil {
+			return nil, err
+		} else if cell != nil {
+			obs[name] = cell
+		}
+	}
+	return &obs, nil
+}
+
+// Returns the value of the column c in the record: a float64 for numeric columns, a string otherwise, nil if missing.
+func (l *csvLayout) parseCell(record []string, c int) (Value, error) {
+	cell, name := strings.TrimSpace(record[c]), l.names[c]
+	switch {
+	case cell == "" && name == l.target:
+		return nil, errors.New("column " + name + ": missing target value.")
+	case cell == "":
+		return nil, nil
+	case name == "":
+		return nil, errors.New("column " + strconv.Itoa(c+1) + " has a value but no name.")
+	case l.kinds[name] != NumericAttribute:
+		return cell, nil
+	}
+	v, err := strconv.ParseFloat(cell, 64)
+	if err != nil {
+		return nil, errors.New("column " + name + ": invalid numeric value \"" + cell + "\".")
+	}
+	return v, nil
+}
+
+// Incremental inference of the kind of a column from its values (see ReadCSV).
+type kindInference struct {
+	numeric  bool            // A value parses as a number
+	text     bool            // A value does not parse as a number
+	repeated bool            // A value not parsing as a number occurs more than once
+	distinct map[string]bool // The values not parsing as numbers seen so far, until one of them repeats
+}
+
+// Adds a cell of the column to the inference.
+func (k *kindInference) add(cell string) {
+	if cell = strings.TrimSpace(cell); cell == "" {
+		return
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		k.numeric = true
+		return
+	}
+	k.text = true
+	if !k.repeated {
+		if k.distinct == nil {
+			k.distinct = map[string]bool{}
+		}
+		k.repeated, k.distinct[strings.Clone(cell)] = k.distinct[cell], true // the cell may be a slice of a reused line
+		if k.repeated {
+			k.distinct = nil
+		}
+	}
+}
+
+// Returns the kind of the column inferred from the values added so far.
+func (k *kindInference) kind() AttributeKind {
+	switch {
+	case !k.numeric && !k.text: // no values to predict from
+		return IDAttribute
+	case !k.text:
+		return NumericAttribute
+	case !k.numeric && !k.repeated:
+		return IDAttribute
+	}
+	return CategoricalAttribute
+}
@@ -0,0 +1,108 @@
+package decision_tree
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Writes the synthetic observations (see prepareSyntheticObservations) to a CSV file with an ID column, returning its path.
+func writeSyntheticCSV(tst *testing.T, n int, seed int64) string {
+	lines := []string{"#id,feature1,feature2,feature3," + TARGET_KEY}
+	for i, obs := range prepareSyntheticObservations(n, 0.1, seed) {
+		line := []string{"row" + strconv.Itoa(i)}
+		for _, attribute := range []string{"feature1", "feature2", "feature3", TARGET_KEY} {
+			line = append(line, strconv.FormatFloat((*obs)[attribute].(float64), 'g', -1, 64))
+		}
+		lines = append(lines, strings.Join(line, ","))
+	}
+
+	path := filepath.Join(tst.TempDir(), "synthetic.csv")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		tst.Fatalf("Writing the CSV file failed: %s", err.Error())
+	}
+	return path
+}
+
+func Test_ReadCSVFile(tst *testing.T) {
+	d, err := ReadCSVFile(writeSyntheticCSV(tst, 300, 14), &CSVSchema{TargetAttribute: TARGET_KEY})
+	if err != nil {
+		tst.Fatalf("Reading the CSV file failed: %s", err.Error())
+	}
+	if len(d.Observations) != 300 || strings.Join(d.Predictors, ",") != "feature1,feature2,feature3" || d.Kinds["#id"] != IDAttribute ||
+		d.Kinds[TARGET_KEY] != NumericAttribute || len(d.CategoricalPredictors) != 0 {
+		tst.Errorf("CSV file test failed, got %d observations with predictors %v and kinds %v.", len(d.Observations), d.Predictors, d.Kinds)
+	}
+
+	settings := getSettings("supergrow", "")
+	d.Configure(settings)
+	loaded, expected := new(DecisionTree), new(DecisionTree)
+	loaded.InitRoot(settings, d.Observations)
+	loaded.Expand(true)
+	expected.InitRoot(settings, prepareSyntheticObservations(300, 0.1, 14))
+	expected.Expand(true)
+	if loaded.GetSerializedModel() != expected.GetSerializedModel() {
+		tst.Errorf("CSV file test failed, the trees differ.")
+	}
+
+	// the header row repeats within the file
+	schema := &CSVSchema{TargetAttribute: TARGET_KEY, Kinds: map[string]AttributeKind{TARGET_KEY: NumericAttribute}}
+	if _, err := ReadCSVFile("test_data/data1.csv", schema); err == nil || err.Error() != "Line 2002, column __target: invalid numeric value \"__target\"." {
+		tst.Errorf("CSV file error test failed, got '%v'.", err)
+	}
+}
+
+func Test_ReadCSV(tst *testing.T) {
+	data := "name,country,size,label\nA,CZ,1.5,yes\nB,SK,,no\nC,CZ,3,yes\nD,AT, 4 ,no\n"
+	d, err := ReadCSV(strings.NewReader(data), &CSVSchema{TargetAttribute: "label"})
+	if err != nil {
+		tst.Fatalf("Reading CSV failed: %s", err.Error())
+	}
+	first, second := *d.Observations[0], *d.Observations[1]
+	if strings.Join(d.Predictors, ",") != "country,size" || strings.Join(d.CategoricalPredictors, ",") != "country" ||
+		d.Kinds["name"] != IDAttribute || first["size"] != 1.5 || first["label"] != "yes" || !isMissing(second["size"]) {
+		tst.Errorf("CSV schema inference test failed, got %v, %v and %v.", d.Kinds, first, second)
+	}
+
+	schema := &CSVSchema{TargetAttribute: "label", Kinds: map[string]AttributeKind{"name": CategoricalAttribute, "size": CategoricalAttribute}}
+	if d, err = ReadCSV(strings.NewReader(data), schema); err != nil || (*d.Observations[0])["size"] != "1.5" || len(d.CategoricalPredictors) != 3 {
+		tst.Errorf("CSV explicit schema test failed (%v).", err)
+	}
+
+	// only the values not parsing as numbers are remembered
+	inference := kindInference{}
+	for _, cell := range []string{"1", "1", "2", "x", "y"} {
+		inference.add(cell)
+	}
+	if inference.kind() != CategoricalAttribute || len(inference.distinct) != 2 {
+		tst.Errorf("CSV kind inference test failed, got %v remembering %v.", inference.kind(), inference.distinct)
+	}
+
+	// columns mixing numbers and text are categorical predictors, columns without values are no predictors
+	mixed := "id,code,empty,label\nA,1,,yes\nB,1,,no\nC,2, ,yes\nD,x,,no\nE,y,,yes\n"
+	if d, err = ReadCSV(strings.NewReader(mixed), &CSVSchema{TargetAttribute: "label"}); err != nil ||
+		strings.Join(d.Predictors, ",") != "code" || strings.Join(d.CategoricalPredictors, ",") != "code" || d.Kinds["empty"] != IDAttribute ||
+		(*d.Observations[0])["code"] != "1" || (*d.Observations[3])["code"] != "x" {
+		tst.Errorf("CSV mixed and empty column test failed, got %v (%v).", d, err)
+	}
+
+	cases := []struct {
+		data     string
+		schema   *CSVSchema
+		expected string
+	}{
+		{"a,b\n1,x\n2,y\n", &CSVSchema{TargetAttribute: "c"}, "Target column c not found."},
+		{"a,b\n1,x\n2,y\n", nil, "A schema with a target attribute is required."},
+		{"a,b\n1,x\n,y\n", &CSVSchema{TargetAttribute: "a"}, "Line 3, column a: missing target value."},
+		{"a,b\n1,x\nz,2\n", &CSVSchema{TargetAttribute: "b", Kinds: map[string]AttributeKind{"a": NumericAttribute}}, "Line 3, column a: invalid numeric value \"z\"."},
+		{"a,b\n1,x\n2\n", &CSVSchema{TargetAttribute: "a"}, "record on line 3: wrong number of fields"},
+		{"a,,b\n1,,x\n2,3,y\n", &CSVSchema{TargetAttribute: "a"}, "Line 3, column 2 has a value but no name."},
+	}
+	for _, c := range cases {
+		if _, err := ReadCSV(strings.NewReader(c.data), c.schema); err == nil || err.Error() != c.expected {
+			tst.Errorf("CSV error test failed. Expected '%s', got '%v'.", c.expected, err)
+		}
+	}
+}
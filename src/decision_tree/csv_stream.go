@@ -0,0 +1,230 @@
+package decision_tree
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reads observations from CSV data one row at a time, for data too large to be held in memory as observations:
+//
+//	reader, err := NewCSVReader(file, schema)
+//	for reader.Next() {
+//		prediction, err := tree.Classify(reader.Observation())
+//	}
+//	err = reader.Err()
+//
+// Rows can also be read into a columnar Dataset (see ReadDataset).
+type CSVReader struct {
+	reader *csv.Reader
+	layout *csvLayout
+	record []string
+	line   int
+	obs    *Observation
+	err    error
+}
+
+// Returns a reader of the CSV data whose first row holds the attribute names (see ReadCSV). As the rows are not known
+// in advance, the columns left out of the schema are IDs if named #... or __... and numeric otherwise; InferCSVSchema
+// infers the kinds from the values in a separate pass over the data.
+func NewCSVReader(r io.Reader, schema *CSVSchema) (*CSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("Missing CSV header.")
+	} else if err != nil {
+		return nil, err
+	}
+
+	layout, err := newCSVLayout(header, schema, func(c int) AttributeKind { return NumericAttribute })
+	if err != nil {
+		return nil, err
+	}
+	return &CSVReader{reader: reader, layout: layout}, nil
+}
+
+// Infers the kinds of all columns of the CSV data as ReadCSV does, reading the data one row at a time.
+// Only the values not parsing as numbers are remembered, until one of them repeats.
+func InferCSVSchema(r io.Reader, targetAttribute string) (*CSVSchema, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("Missing CSV header.")
+	} else if err != nil {
+		return nil, err
+	}
+	header = append([]string{}, header...)
+
+	inferences := make([]kindInference, len(header))
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for c, cell := range record {
+			inferences[c].add(cell)
+		}
+	}
+
+	schema := &CSVSchema{TargetAttribute: targetAttribute}
+	layout, err := newCSVLayout(header, schema, func(c int) AttributeKind { return inferences[c].kind() })
+	if err != nil {
+		return nil, err
+	}
+	schema.Kinds = layout.kinds
+	return schema, nil
+}
+
+// Sets the target attribute, the predictors and the categorical predictors of the grow options to those of the CSV data.
+// The weight attribute of the grow options (if set) is left out of the predictors.
+func (r *CSVReader) Configure(options *Options) {
+	configure(options, r.layout.target, r.layout.predictors, r.layout.categorical)
+}
+
+// Advances the reader to the next row, returning false at the end of the data or on an error (see Err).
+func (r *CSVReader) Next() bool {
+	r.obs = nil
+	if !r.nextRecord() {
+		return false
+	}
+	if r.obs, r.err = r.layout.parseRecord(r.record); r.err != nil {
+		r.err = r.lineError(r.err)
+		return false
+	}
+	return true
+}
+
+// Returns the observation of the current row.
+func (r *CSVReader) Observation() *Observation {
+	return r.obs
+}
+
+// Returns the line of the current row in the CSV data.
+func (r *CSVReader) Line() int {
+	return r.line
+}
+
+// Returns the error that stopped the reader, or nil if it reached the end of the data.
+func (r *CSVReader) Err() error {
+	return r.err
+}
+
+// Reads the next record of the CSV data.
+func (r *CSVReader) nextRecord() bool {
+	if r.err != nil {
+		return false
+	}
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return false
+	} else if err != nil {
+		r.err = err
+		return false
+	}
+	r.record = record
+	r.line, _ = r.reader.FieldPos(0)
+	return true
+}
+
+// Returns the error of the current row, prefixed by its line.
+func (r *CSVReader) lineError(err error) error {
+	return errors.New("Line " + strconv.Itoa(r.line) + ", " + err.Error())
+}
+
+// Reads the remaining rows into a columnar dataset without building their observations (see NewDataset): a typed column
+// for every predictor of the grow options, the target attribute as the target column and the weight attribute (if any)
+// as the weights. Every CSV column is read into one of them at most. Together with InferCSVSchema, this trains trees
+// on data read twice from a stream (see GrowDataset), holding the values in compact columns only.
+func (r *CSVReader) ReadDataset(options *Options) (*Dataset, error) {
+	columns := map[int]*Column{} // Columns of the dataset by their position in the CSV data
+	newColumn := func(name string) (*Column, error) {
+		for c, column := range r.layout.names {
+			if column == name && columns[c] != nil {
+				return nil, errors.New("Column " + name + " is read more than once (as a predictor, the target or the weights).")
+			} else if column == name && column != "" {
+				columns[c] = &Column{Name: name, Type: FloatColumn}
+				if r.layout.kinds[name] != NumericAttribute {
+					columns[c].Type = StringColumn
+				}
+				return columns[c], nil
+			}
+		}
+		return nil, errors.New("Column " + name + " not found.")
+	}
+
+	d := &Dataset{}
+	for _, predictor := range *options.Predictors {
+		column, err := newColumn(predictor)
+		if err != nil {
+			return nil, err
+		}
+		d.Columns = append(d.Columns, column)
+	}
+	var err error
+	if d.Target, err = newColumn(options.TargetAttribute); err != nil {
+		return nil, err
+	}
+	if options.WeightAttribute != "" {
+		if d.Weights, err = newColumn(options.WeightAttribute); err != nil {
+			return nil, err
+		}
+		if d.Weights.Type != FloatColumn {
+			return nil, errors.New("Weights must be float values.")
+		}
+	}
+
+	// the values of the categorical columns are interned, the other strings copied, so that the records can be dropped
+	interned := map[*Column]map[string]string{}
+	for _, column := range columns {
+		if r.layout.kinds[column.Name] == CategoricalAttribute {
+			interned[column] = map[string]string{}
+		}
+	}
+
+	for rows := 0; r.nextRecord(); rows++ {
+		for c, column := range columns {
+			val, err := r.layout.parseCell(r.record, c)
+			if err != nil {
+				return nil, r.lineError(err)
+			}
+			if val == nil && column == d.Target {
+				return nil, r.lineError(errors.New("column " + column.Name + ": missing target value."))
+			} else if val == nil && column == d.Weights {
+				return nil, r.lineError(errors.New("column " + column.Name + ": missing weight."))
+			} else if val == nil && column.Missing == nil {
+				column.Missing = make([]bool, rows, rows+1)
+			}
+			if column.Missing != nil {
+				column.Missing = append(column.Missing, val == nil)
+			}
+
+			switch v := val.(type) {
+			case float64:
+				column.Floats = append(column.Floats, v)
+			case string:
+				if values, ok := interned[column]; !ok {
+					v = strings.Clone(v)
+				} else if value, ok := values[v]; ok {
+					v = value
+				} else {
+					v = strings.Clone(v)
+					values[v] = v
+				}
+				column.Strings = append(column.Strings, v)
+			case nil: // a placeholder of the missing value
+				if column.Type == FloatColumn {
+					column.Floats = append(column.Floats, 0.0)
+				} else {
+					column.Strings = append(column.Strings, "")
+				}
+			}
+		}
+	}
+	return d, r.err
+}
@@ -0,0 +1,107 @@
+package decision_tree
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_CSVReader(tst *testing.T) {
+	path := writeSyntheticCSV(tst, 1000, 15)
+	open := func() *os.File {
+		file, err := os.Open(path)
+		if err != nil {
+			tst.Fatalf("Opening the CSV file failed: %s", err.Error())
+		}
+		return file
+	}
+
+	// first pass: schema inference, second pass: columnar dataset
+	file := open()
+	schema, err := InferCSVSchema(file, TARGET_KEY)
+	file.Close()
+	if err != nil || schema.Kinds["#id"] != IDAttribute || schema.Kinds["feature1"] != NumericAttribute {
+		tst.Fatalf("CSV schema inference test failed, got %v (%v).", schema, err)
+	}
+	file = open()
+	reader, err := NewCSVReader(file, schema)
+	if err != nil {
+		tst.Fatalf("Opening the CSV reader failed: %s", err.Error())
+	}
+	settings := getSettings("supergrow", "")
+	settings.MaxDepth = 5
+	reader.Configure(settings)
+	d, err := reader.ReadDataset(settings)
+	file.Close()
+	if err != nil || d.Len() != 1000 {
+		tst.Fatalf("Reading the CSV dataset failed (%v).", err)
+	}
+
+	streamed, expected := new(DecisionTree), new(DecisionTree)
+	if err := streamed.GrowDataset(settings, d); err != nil {
+		tst.Fatalf("Growing the tree from the CSV dataset failed: %s", err.Error())
+	}
+	loaded, _ := ReadCSVFile(path, &CSVSchema{TargetAttribute: TARGET_KEY})
+	expected.InitRoot(settings, loaded.Observations)
+	expected.Expand(true)
+	if streamed.GetSerializedModel() != expected.GetSerializedModel() {
+		tst.Errorf("CSV streaming test failed, the trees differ.")
+	}
+
+	// batch scoring
+	file = open()
+	defer file.Close()
+	reader, _ = NewCSVReader(file, schema)
+	rows, successes := 0, 0
+	for reader.Next() {
+		got, err := streamed.Classify(reader.Observation())
+		if isEq, _ := _eq(got, (*reader.Observation())[TARGET_KEY]); err == nil && isEq {
+			successes++
+		}
+		rows++
+	}
+	if reader.Err() != nil || rows != 1000 || successes < 850 {
+		tst.Errorf("CSV batch scoring test failed, %d/%d rows classified correctly (%v).", successes, rows, reader.Err())
+	}
+}
+
+func Test_CSVReaderErrors(tst *testing.T) {
+	reader, _ := NewCSVReader(strings.NewReader("a,b\n1,2\n3,x\n"), &CSVSchema{TargetAttribute: "a"})
+	rows := 0
+	for reader.Next() {
+		rows++
+	}
+	if err := reader.Err(); rows != 1 || err == nil || err.Error() != "Line 3, column b: invalid numeric value \"x\"." {
+		tst.Errorf("CSV reader error test failed, got %d rows and '%v'.", rows, err)
+	}
+
+	settings := &Options{TargetAttribute: "a", Predictors: &[]string{"b"}}
+	reader, _ = NewCSVReader(strings.NewReader("a,b\n1,2\n3,\n"), &CSVSchema{TargetAttribute: "a"})
+	if d, err := reader.ReadDataset(settings); err != nil || !d.Columns[0].IsMissing(1) || d.Columns[0].IsMissing(0) {
+		tst.Errorf("CSV dataset missing value test failed (%v).", err)
+	}
+	reader, _ = NewCSVReader(strings.NewReader("a,b\n1,2\n,3\n"), &CSVSchema{TargetAttribute: "a"})
+	if _, err := reader.ReadDataset(settings); err == nil || err.Error() != "Line 3, column a: missing target value." {
+		tst.Errorf("CSV dataset error test failed, got '%v'.", err)
+	}
+
+	// the weights are no predictor
+	settings = &Options{WeightAttribute: "w"}
+	reader, _ = NewCSVReader(strings.NewReader("a,b,w\n1,2,0.5\n0,3,2\n"), &CSVSchema{TargetAttribute: "a"})
+	reader.Configure(settings)
+	if d, err := reader.ReadDataset(settings); err != nil || len(*settings.Predictors) != 1 || d.Weights.Floats[1] != 2.0 {
+		tst.Errorf("CSV dataset weights test failed, got predictors %v (%v).", *settings.Predictors, err)
+	}
+	settings.Predictors = &[]string{"b", "w"}
+	reader, _ = NewCSVReader(strings.NewReader("a,b,w\n1,2,0.5\n"), &CSVSchema{TargetAttribute: "a"})
+	if _, err := reader.ReadDataset(settings); err == nil {
+		tst.Errorf("CSV dataset error test failed, the weights read as a predictor.")
+	}
+
+	if _, err := NewCSVReader(strings.NewReader("a,b\n1,2\n"), nil); err == nil || err.Error() != "A schema with a target attribute is required." {
+		tst.Errorf("CSV reader schema test failed, got '%v'.", err)
+	}
+	if _, err := InferCSVSchema(strings.NewReader("a,b\n1,2\n"), ""); err == nil || err.Error() != "A schema with a target attribute is required." {
+		tst.Errorf("CSV schema inference test failed, got '%v'.", err)
+	}
+}
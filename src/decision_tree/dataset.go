@@ -0,0 +1,217 @@
+package decision_tree
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Types of the columns of a Dataset.
+type ColumnType int
+
+const (
+	FloatColumn ColumnType = iota
+	IntColumn
+	StringColumn
+)
+
+// A typed column of a Dataset. Only the slice matching the type of the column holds the values; Missing marks the rows
+// without a value (nil means that no value is missing).
+type Column struct {
+	Name    string
+	Type    ColumnType
+	Floats  []float64
+	Ints    []int
+	Strings []string
+	Missing []bool
+}
+
+// A columnar set of observations: typed predictor columns, the target column and optionally the weights of the rows.
+// Trees can be grown from a Dataset directly (see GrowDataset), without the map lookups and type switches
+// of the map-based Observations.
+type Dataset struct {
+	Columns []*Column
+	Target  *Column
+	Weights *Column // Weights of the rows (a FloatColumn); nil means unit weights
+}
+
+// Returns the number of rows of the column.
+func (c *Column) Len() int {
+	switch c.Type {
+	case IntColumn:
+		return len(c.Ints)
+	case StringColumn:
+		return len(c.Strings)
+	}
+	return len(c.Floats)
+}
+
+// Returns true iff the value of the column is missing in the given row.
+func (c *Column) IsMissing(row int) bool {
+	return c.Missing != nil && c.Missing[row]
+}
+
+// Returns the value of the column in the given row as an observation value (nil if missing).
+func (c *Column) Value(row int) Value {
+	if c.IsMissing(row) {
+		return nil
+	}
+	switch c.Type {
+	case IntColumn:
+		return c.Ints[row]
+	case StringColumn:
+		return c.Strings[row]
+	}
+	return c.Floats[row]
+}
+
+// Returns the number of rows of the dataset.
+func (d *Dataset) Len() int {
+	return d.Target.Len()
+}
+
+// Checks that the predictor columns, the target column and the weights (float values) of the dataset all hold its number
+// of rows.
+func (d *Dataset) check() error {
+	columns := append([]*Column{d.Target}, d.Columns...)
+	if d.Weights != nil {
+		if d.Weights.Type != FloatColumn || d.Weights.Missing != nil {
+			return errors.New("Weights must be float values.")
+		}
+		columns = append(columns, d.Weights)
+	}
+	for _, column := range columns {
+		if column.Len() != d.Len() || (column.Missing != nil && len(column.Missing) != d.Len()) {
+			return errors.New("Column " + column.Name + " does not have " + strconv.Itoa(d.Len()) + " rows.")
+		}
+	}
+	return nil
+}
+
+// Returns the column of the given name, or nil if there is no such column.
+func (d *Dataset) Column(name string) *Column {
+	for _, column := range d.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+// Builds a dataset from the observations, with a column for every predictor of the grow options, the target attribute
+// as the target column and the weight attribute (if any) as the weights. The type of every column is given by its values,
+// which must all be float64 (or float32), int or string; missing predictor values are marked as missing.
+func NewDataset(observations []*Observation, options *Options) (*Dataset, error) {
+	d := &Dataset{}
+	for _, predictor := range *options.Predictors {
+		column, err := newColumn(observations, predictor)
+		if err != nil {
+			return nil, err
+		}
+		d.Columns = append(d.Columns, column)
+	}
+
+	var err error
+	if d.Target, err = newColumn(observations, options.TargetAttribute); err != nil {
+		return nil, err
+	}
+	if d.Target.Missing != nil {
+		return nil, errors.New("Missing target value.")
+	}
+	if options.WeightAttribute != "" {
+		if d.Weights, err = newColumn(observations, options.WeightAttribute); err != nil {
+			return nil, err
+		}
+		if d.Weights.Type != FloatColumn || d.Weights.Missing != nil {
+			return nil, errors.New("Weights must be float values.")
+		}
+	}
+	return d, nil
+}
+
+// Returns the observations holding the rows of the dataset.
+func (d *Dataset) Observations() []*Observation {
+	observations := make([]*Observation, d.Len())
+	for row := range observations {
+		observations[row] = d.observation(row)
+	}
+	return observations
+}
+
+// Returns the observation holding the given row of the dataset.
+func (d *Dataset) observation(row int) *Observation {
+	obs := Observation{d.Target.Name: d.Target.Value(row)}
+	for _, column := range d.Columns {
+		if !column.IsMissing(row) {
+			obs[column.Name] = column.Value(row)
+		}
+	}
+	if d.Weights != nil {
+		obs[d.Weights.Name] = d.Weights.Floats[row]
+	}
+	return &obs
+}
+
+// Builds a typed column from the values of the attribute in the observations.
+func newColumn(observations []*Observation, attribute string) (*Column, error) {
+	column := &Column{Name: attribute, Type: FloatColumn}
+	for _, obs := range observations {
+		val := (*obs)[attribute]
+		if isMissing(val) {
+			continue
+		}
+		if _, ok := val.(int); ok {
+			column.Type = IntColumn
+		} else if _, ok := val.(string); ok {
+			column.Type = StringColumn
+		}
+		break
+	}
+
+	for row, obs := range observations {
+		val := (*obs)[attribute]
+		if isMissing(val) {
+			if column.Missing == nil {
+				column.Missing = make([]bool, len(observations))
+			}
+			column.Missing[row] = true
+		}
+
+		switch v := val.(type) {
+		case float64:
+			if column.Type == FloatColumn {
+				column.Floats = append(column.Floats, v)
+				continue
+			}
+		case float32:
+			if column.Type == FloatColumn {
+				column.Floats = append(column.Floats, float64(v))
+				continue
+			}
+		case int:
+			if column.Type == IntColumn {
+				column.Ints = append(column.Ints, v)
+				continue
+			}
+		case string:
+			if column.Type == StringColumn {
+				column.Strings = append(column.Strings, v)
+				continue
+			}
+		case nil: // a placeholder of the missing value
+			column.Floats, column.Ints, column.Strings = append(column.Floats, 0.0), append(column.Ints, 0), append(column.Strings, "")
+			continue
+		}
+		return nil, errors.New("Column " + attribute + " mixes types or holds unsupported values.")
+	}
+
+	// only the values of the column type are kept
+	switch column.Type {
+	case FloatColumn:
+		column.Ints, column.Strings = nil, nil
+	case IntColumn:
+		column.Floats, column.Strings = nil, nil
+	case StringColumn:
+		column.Floats, column.Ints = nil, nil
+	}
+	return column, nil
+}
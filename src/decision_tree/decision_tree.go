@@ -2,7 +2,9 @@ package decision_tree
 
 import (
 	"errors"
+	"math/rand"
 	"sort"
+	"sync"
 )
 
 const NO_PREDICTOR string = "__noPredictor__"
@@ -21,19 +23,67 @@ type DecisionTree struct {
 	SplitValue     Value   // The value of the split predictor to split on; smaller valued obserations continue to the left subtree, larger to the right subtree
 	Classification Value   // For leaf nodes denotes the predicted class; value is NO_CLASSIFICATION in internal nodes
 
+	ClassCounts map[Value]float64 // Distribution of target classes (total sample weights) among the training observations of the node (classification trees)
+
+	Surrogates      []*SurrogateSplit // Surrogate splits routing observations missing the split predictor, best first
+	MissingGoesLeft bool              // Direction of observations missing the split predictor and all surrogate predictors
+
 	impurity  *float64 // Measure of the node (less is better)
 	_sortedBy *string
+	seed      int64 // Seed of the random choices made in this node (derived from Options.Seed)
+
+	grow       *growState   // State shared by all nodes of the tree while it grows (set up by InitRoot)
+	histograms []*histogram // Histograms of the binned predictors in this node (histogram mode), indexed as the binning
+}
+
+// State shared by all nodes of a tree while it grows.
+type growState struct {
+	classWeights map[Value]float64 // Weights of the target classes, resolved from the grow options at the root
+	rootWeight   float64           // Total weight of the root observations
+	binning      *binning          // Quantile bins of the numeric predictors (histogram mode)
+
+	mu     sync.Mutex // Guards the leaf count when subtrees grow concurrently
+	leaves int        // Number of leaves of the tree grown so far (including the leaves reserved for the splits in progress)
+}
+
+// Returns the number of leaves of the tree grown so far; nodes grown without a root (see InitRoot) count none.
+func (g *growState) leafCount() int {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.leaves
+}
+
+// Reserves the leaf added by splitting a node, unless the tree already has maxLeaves leaves (0 means no limit).
+// Output: true iff the node may be split.
+func (g *growState) reserveLeaf(maxLeaves int) bool {
+	if g == nil {
+		return true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if maxLeaves > 0 && g.leaves >= maxLeaves {
+		return false
+	}
+	g.leaves++
+	return true
 }
 
 // Initializes the provided node and sets the pointers so that it is the left child of the current node.
 func (t *DecisionTree) setLeft(child *DecisionTree) {
 	child.initNode(t.Options, t.Depth+1)
+	child.seed = deriveSeed(t.seed, 1)
+	child.grow = t.grow
 	t.left = child
 }
 
 // Initializes the provided node and sets the pointers so that it is the right child of the current node.
 func (t *DecisionTree) setRight(child *DecisionTree) {
 	child.initNode(t.Options, t.Depth+1)
+	child.seed = deriveSeed(t.seed, 2)
+	child.grow = t.grow
 	t.right = child
 }
 
@@ -48,36 +98,127 @@ func (t *DecisionTree) Right() *DecisionTree {
 }
 
 // Finds the best possible splitting parameters for the given node by testing all eligible splits on all eligible predictors.
+// With Options.SplitWorkers > 1, the predictors are evaluated concurrently (see findBestSplitConcurrently).
+// The best split is rejected (NO_PREDICTOR is returned) if it decreases the impurity by less than Options.MinImpurityDecrease.
 // Returns: <predictor to split upon>  <index to split upon> <gini impurity of the split>
 // Side-effects: Re-orders the observations within the node.
 func (t *DecisionTree) FindBestSplit() (bestPredictor string, bestIndex *int, bestPurity *float64, err error) {
 	bestPredictor, bestIndex, bestPurity = NO_PREDICTOR, nil, nil
+	if t.grow != nil && t.grow.binning != nil && t.histograms == nil { // shared by the concurrent split workers
+		t.histograms = make([]*histogram, len(t.grow.binning.thresholds))
+	}
 
-	for _, predictor := range *t.Options.Predictors {
-		index, purity, err1 := t.bestSplitWithPredictor(predictor)
-		if err1 == nil {
-			if (bestPurity == nil && purity != nil) || (purity != nil && *purity < *bestPurity) {
-				bestPurity, bestIndex, bestPredictor = purity, index, predictor
+	candidates := t.candidatePredictors()
+	if t.Options.SplitWorkers > 1 && len(candidates) > 1 {
+		if bestPredictor, bestIndex, bestPurity, err = t.findBestSplitConcurrently(candidates); err != nil {
+			return NO_PREDICTOR, nil, nil, err
+		}
+	} else {
+		for _, predictor := range candidates {
+			index, purity, err1 := t.bestSplitWithPredictor(predictor)
+			if err1 == nil {
+				if (bestPurity == nil && purity != nil) || (purity != nil && *purity < *bestPurity) {
+					bestPurity, bestIndex, bestPredictor = purity, index, predictor
+				}
+			} else {
+				err = err1
+				return
 			}
-		} else {
-			err = err1
-			return
+		}
+	}
+
+	if bestPurity != nil && t.Options.MinImpurityDecrease > 0.0 {
+		decrease, err := t.impurityDecrease(*bestPurity)
+		if err != nil {
+			return NO_PREDICTOR, nil, nil, err
+		}
+		if decrease < t.Options.MinImpurityDecrease {
+			return NO_PREDICTOR, nil, nil, nil
 		}
 	}
 	return
 }
 
-// Sorts the observations in this node according to the values of the given predictor.
+// Returns the weighted impurity decrease of splitting this node with the given split purity, as defined by CART:
+// N_t / N * (impurity - split purity), N_t being the weight of the node and N the weight of the root.
+func (t *DecisionTree) impurityDecrease(purityAtSplit float64) (float64, error) {
+	impurity, err := t.Impurity()
+	if err != nil {
+		return 0.0, err
+	}
+	return t.weightedDecrease(t.totalWeight(t.Observations), impurity-purityAtSplit), nil
+}
+
+// Scales the impurity decrease of splitting this node, weighing N, by the fraction of the root weight it holds.
+func (t *DecisionTree) weightedDecrease(N float64, decrease float64) float64 {
+	if t.grow != nil && t.grow.rootWeight > 0.0 {
+		return N / t.grow.rootWeight * decrease
+	}
+	return decrease
+}
+
+// Returns the predictors eligible for splitting this node: all of the predictors, or a random subset of MaxFeatures
+// predictors (kept in their original order) if the grow options limit their number.
+func (t *DecisionTree) candidatePredictors() []string {
+	predictors, k := *t.Options.Predictors, t.Options.MaxFeatures
+	if k <= 0 || k >= len(predictors) {
+		return predictors
+	}
+
+	picked := rand.New(rand.NewSource(t.seed)).Perm(len(predictors))[:k]
+	sort.Ints(picked)
+	candidates := make([]string, k)
+	for i, index := range picked {
+		candidates[i] = predictors[index]
+	}
+	return candidates
+}
+
+// Sorts the observations in this node according to the values of the given predictor (or their bins in histogram mode).
 func (t *DecisionTree) sortByPredictor(predictor string) {
 	if t._sortedBy == nil || *t._sortedBy != predictor {
-		sortFunc := ByPredictorValueFloat{predictor, &t.Observations}
-		sort.Sort(sortFunc)
+		if t.isBinned(predictor) {
+			t.sortByBin(predictor)
+		} else if t.isCategorical(predictor) {
+			sort.Sort(ByPredictorRank{predictor, t.categoryRanks(predictor), &t.Observations})
+		} else {
+			sort.Sort(ByPredictorValueFloat{predictor, &t.Observations})
+		}
 		t._sortedBy = &predictor
 	}
 }
 
+// Returns the best split of this node on the given predictor, as found by the split strategy among observations having
+// a value for the predictor. Observations missing the predictor do not benefit from the split, so the purity of the split
+// is blended with the node impurity proportionally to the fraction of such observations.
 func (t *DecisionTree) bestSplitWithPredictor(predictor string) (splitAtIndex *int, purityAtSplit *float64, err error) {
-	return t.Options.SplitStrategy.SplitPurity(predictor, t.Options.TargetAttribute, t)
+	if t.isBinned(predictor) {
+		splitAtIndex, purityAtSplit, err = t.histogramSplit(predictor)
+	} else {
+		splitAtIndex, purityAtSplit, err = t.Options.SplitStrategy.SplitPurity(predictor, t.Options.TargetAttribute, t)
+	}
+	if err != nil || purityAtSplit == nil {
+		return
+	}
+
+	present, total := 0.0, 0.0
+	for _, obs := range t.Observations {
+		if !isMissing((*obs)[predictor]) {
+			present += t.weightOf(obs)
+		}
+		total += t.weightOf(obs)
+	}
+
+	if present < total {
+		impurity, err := t.Impurity()
+		if err != nil {
+			return nil, nil, err
+		}
+		fraction := present / total
+		blended := fraction*(*purityAtSplit) + (1-fraction)*impurity
+		purityAtSplit = &blended
+	}
+	return
 }
 
 // Initializes the node so that it can be safely used within the tree.
@@ -95,36 +236,49 @@ func (t *DecisionTree) Impurity() (float64, error) {
 	if t.impurity != nil {
 		return *t.impurity, nil
 	}
-	fn := t.Options.SplitStrategy.SlicePurity
-	val, err := fn(t.Observations[:], t.Options.TargetAttribute)
+	val, err := t.SlicePurity(t.Observations)
 	t.impurity = &val
 	return *t.impurity, err
 }
 
+// Returns the impurity of the given observations as measured by the split strategy of this tree. Unlike the SlicePurity
+// of the split strategy itself, which counts every observation once, the observations count with their weights in this
+// tree (see Options.WeightAttribute and Options.ClassWeights), so that the impurity of the node's own observations
+// is its Impurity. Split strategies other than the built-in ones do not support weighted observations.
+func (t *DecisionTree) SlicePurity(data []*Observation) (float64, error) {
+	switch metric := t.Options.SplitStrategy.(type) { // built-in metrics honour observation weights
+	case scanningPurityMetric:
+		return accumulatedPurity(t.newAccumulator(metric), data, t.Options.TargetAttribute, t.weightOf)
+	case weightedPurityMetric:
+		return metric.weightedSlicePurity(data, t.Options.TargetAttribute, t.weightOf)
+	}
+	if t.isWeighted() {
+		return 0.0, errors.New("The split strategy does not support weighted observations.")
+	}
+	return t.Options.SplitStrategy.SlicePurity(data, t.Options.TargetAttribute)
+}
+
 // Initializes the root node. Note: always call this function before first expanding on a node.
 func (t *DecisionTree) InitRoot(growOptions *Options, observations []*Observation) error {
 	if len(observations) == 0 {
 		return nil
 	}
 
-	if !t.testPredictorsPresent() {
-		return errors.New("Not all observations contain values for all predictors.")
+	if err := checkWeights(observations, growOptions); err != nil {
+		return err
 	}
 
 	t.Observations = observations
 	t.initNode(growOptions, 0)
-	return nil
-}
-
-func (t *DecisionTree) testPredictorsPresent() bool {
-	for _, obs := range t.Observations {
-		for _, pred := range *t.Options.Predictors {
-			if _, ok := (*obs)[pred]; !ok {
-				return false
-			}
-		}
+	t.seed = growOptions.Seed
+	t.grow = &growState{leaves: 1}
+	classWeights, err := t.resolveClassWeights()
+	t.grow.classWeights = classWeights
+	t.grow.rootWeight = t.totalWeight(observations)
+	if err == nil && growOptions.HistogramBins > 0 {
+		t.grow.binning, err = t.newBinning(observations)
 	}
-	return true
+	return err
 }
 
 func (t *DecisionTree) isGrowable() (bool, error) {
@@ -132,106 +286,181 @@ func (t *DecisionTree) isGrowable() (bool, error) {
 		return false, err
 	}
 
-	// check for grow-stop conditions
-	N := len(t.Observations)
+	return t.isGrowableWith(t.sampleSize(t.Observations)), nil
+}
+
+// Checks the grow-stop conditions for this node (with memoized impurity), the size of the node N being the total sample
+// weight of its observations.
+func (t *DecisionTree) isGrowableWith(N float64) bool {
 	options := t.Options
 
 	tooDeep := t.Depth >= options.MaxDepth
 	pureEnough := *t.impurity < options.MaxSplitImpurity
-	tooSpecific := N < options.MinSplitSize
-	return !tooDeep && !pureEnough && !tooSpecific, nil
+	tooSpecific := N < float64(options.MinSplitSize) || N < 2*float64(options.MinLeafSize)
+	tooManyLeaves := options.MaxLeafNodes > 0 && t.grow.leafCount() >= options.MaxLeafNodes
+	return !tooDeep && !pureEnough && !tooSpecific && !tooManyLeaves
 }
 
 // expands the given node (if possible and allowed by the provided grow options setting) by finding the best split and performing it.
 // If auto == true, this process is recursive, growing a full tree (one where calling Expand(*) on any of the nodes produces no further change).
+// The first error met in any of the subtrees is returned.
 func (t *DecisionTree) Expand(auto bool) (err error) {
+	split, err := t.expandNode(auto)
+	if err != nil || !split {
+		return err
+	}
+
+	// automatically continue on child nodes
+	if err := t.left.Expand(true); err != nil {
+		return err
+	}
+	return t.right.Expand(true)
+}
+
+// Expands this node alone: splits it if allowed (canSplit) and possible, or turns it into a leaf.
+// Output: true iff the node was split.
+func (t *DecisionTree) expandNode(canSplit bool) (bool, error) {
+	defer t.releaseHistograms()
+
+	// Remember the class distribution for probability estimates
+	if err := t.rememberClassCounts(); err != nil {
+		return false, err
+	}
 
 	// Find out if we are allowed to expand the tree one more level
 	if canGrow, err := t.isGrowable(); err != nil {
-		return err
-	} else if !auto || !canGrow {
-		t.Classification, err = t.getMajorityVote()
-		return err
+		return false, err
+	} else if !canSplit || !canGrow {
+		t.Classification, err = t.getLeafValue()
+		return false, err
 	}
 
-	// try to find a predictor and either classify if no further split is available, or split
-	if bestPredictor, bestIndex, _, err := t.FindBestSplit(); err != nil {
-		return err
-	} else if bestPredictor == NO_PREDICTOR {
-		t.Classification, err = t.getMajorityVote()
-		return nil
-	} else {
-		err = t.splitNode(bestPredictor, *bestIndex)
-		if err != nil {
-			return err
-		}
+	// try to find a predictor and either classify if no further split is available (or allowed by the leaf budget), or split
+	bestPredictor, bestIndex, _, err := t.FindBestSplit()
+	if err != nil {
+		return false, err
+	}
+	if bestPredictor == NO_PREDICTOR || !t.grow.reserveLeaf(t.Options.MaxLeafNodes) {
+		t.Classification, err = t.getLeafValue()
+		return false, err
 	}
+	return true, t.splitNode(bestPredictor, *bestIndex)
+}
 
-	// automatically continue on child nodes
-	if auto {
-		if t.left != nil {
-			t.left.Expand(true)
-		}
-		if t.right != nil {
-			t.right.Expand(true)
-		}
+// Stores the class distribution of the observations in this node (classification trees only), counting their sample weights.
+func (t *DecisionTree) rememberClassCounts() error {
+	if t.Options.LeafEstimator != nil {
+		return nil
 	}
+	counts, err := t.classCounts(t.sampleWeight)
+	if err != nil {
+		return err
+	}
+	t.ClassCounts = counts.counts
+	return nil
+}
 
-	return
+// Returns the value predicted by this node when it is a leaf, as calculated by the configured leaf estimator.
+func (t *DecisionTree) getLeafValue() (Value, error) {
+	if t.Options.LeafEstimator == nil {
+		return t.getMajorityVote()
+	}
+	if estimator, ok := t.Options.LeafEstimator.(weightedLeafEstimator); ok { // built-in estimators honour observation weights
+		return estimator.estimateWeighted(t.Observations, t.Options.TargetAttribute, t.weightOf)
+	}
+	return t.Options.LeafEstimator.Estimate(t.Observations, t.Options.TargetAttribute)
 }
 
-// Returns 0 iff at least half of the observations in this node have target value 0; 1 otherwise.
+// Returns the most frequent target class among the observations in this node (any number of distinct classes is supported),
+// each observation counting with its weight (including the weight of its class).
+// Ties are resolved in favour of the smaller class, so that e.g. a 0/1 node with equal counts votes 0.
 func (t *DecisionTree) getMajorityVote() (bestVal Value, err error) {
 	if len(t.Observations) == 0 {
 		return nil, errors.New("Cannot vote on an empty node!")
 	}
 
-	votes := map[Value]int{}
-	bestVal, bestVotes := (*t.Observations[0])[t.Options.TargetAttribute], 0
+	counts, err := t.classCounts(t.weightOf)
+	if err != nil {
+		return nil, err
+	}
+	if t.Options.LossMatrix != nil {
+		return counts.cheapest(t.Options.LossMatrix), nil
+	}
+	return counts.majority(), nil
+}
 
+// Returns the distribution of target classes among the observations in this node, each observation counting with the weight
+// given by weightOf.
+func (t *DecisionTree) classCounts(weightOf func(*Observation) float64) (*classCounts, error) {
+	counts := newClassCounts(gini)
 	for _, obs := range t.Observations {
-		thisVal := (*obs)[t.Options.TargetAttribute]
-		votes[thisVal]++
-
-		obsVotes := votes[thisVal]
-		if obsVotes > bestVotes {
-			bestVotes, bestVal = obsVotes, thisVal
+		if err := counts.add((*obs)[t.Options.TargetAttribute], weightOf(obs)); err != nil {
+			return nil, err
 		}
 	}
-	return
+	return counts, nil
 }
 
 // Taking two arguments, the predictor and index, splits the node into two subtrees, left one containing
 // all observations from the 1st smallest up to the index-th smallest, sorted according to the predictor.
-// The right node contains the rest of the observations.
+// The right node contains the rest of the observations; observations missing the predictor are routed
+// by the surrogate splits learned for the node (or by the majority direction).
 func (t *DecisionTree) splitNode(predictor string, index int) error {
-	if index < 0 || index >= len(t.Observations) {
+	t.sortByPredictor(predictor)
+	present := t.presentCount(predictor)
+	if index <= 0 || index >= present {
 		return errors.New("Invalid split index.")
 	}
 
 	// Remember split information
-	t.sortByPredictor(predictor)
 	t.SplitPredictor = &predictor
-	t.SplitValue = (*t.Observations[index])[predictor]
+	if t.isCategorical(predictor) {
+		t.SplitValue = categoriesOf(t.Observations[:index], predictor)
+	} else if t.isBinned(predictor) { // the observations are only sorted by bins
+		t.SplitValue = minimumValue(t.Observations[index:present], predictor)
+	} else {
+		t.SplitValue = (*t.Observations[index])[predictor]
+	}
+	t.MissingGoesLeft = t.totalWeight(t.Observations[:index]) >= t.totalWeight(t.Observations[index:present])
+	if err := t.learnSurrogates(index, present); err != nil {
+		return err
+	}
+
+	// Route observations missing the predictor, keeping both regions contiguous
+	left := append([]*Observation{}, t.Observations[:index]...)
+	right := append([]*Observation{}, t.Observations[index:present]...)
+	for _, obs := range t.Observations[present:] {
+		if goesLeft, err := t.routesLeft(obs); err != nil {
+			return err
+		} else if goesLeft {
+			left = append(left, obs)
+		} else {
+			right = append(right, obs)
+		}
+	}
+	copy(t.Observations, left)
+	copy(t.Observations[len(left):], right)
+	t._sortedBy = nil
 
 	// Set up the child nodes
 	t.setLeft(new(DecisionTree))
 	t.setRight(new(DecisionTree))
-	t.left.Observations = t.Observations[:index]
-	t.right.Observations = t.Observations[index:]
+	t.left.Observations = t.Observations[:len(left)]
+	t.right.Observations = t.Observations[len(left):]
+	if t.histograms != nil {
+		return t.subtractHistograms()
+	}
 	return nil
 }
 
 // Returns the classification for a new observation o.
 func (t *DecisionTree) Classify(o *Observation) (val Value, err error) {
-	feature, val, classification := t.GetRule()
-
 	if t.IsLeaf() {
-		return classification, err
+		return t.Classification, err
 	}
 
 	//Traverse the tree
-	if isLess, err := _lt((*o)[feature], val); isLess && err == nil {
+	if goesLeft, err := t.routesLeft(o); goesLeft && err == nil {
 		return t.left.Classify(o)
 	} else if err != nil {
 		return nil, err
@@ -240,9 +469,69 @@ func (t *DecisionTree) Classify(o *Observation) (val Value, err error) {
 	return t.right.Classify(o)
 }
 
+// Returns the class probabilities for a new observation o, estimated from the class counts of the leaf it falls into.
+// With Options.LaplaceSmoothing = a > 0, the probability of class c is (count(c) + a) / (N + a*K), K being the number of
+// classes known to the root, so that no class seen in training gets a zero probability.
+func (t *DecisionTree) ClassifyProba(o *Observation) (map[Value]float64, error) {
+	leaf := t
+	for !leaf.IsLeaf() {
+		goesLeft, err := leaf.routesLeft(o)
+		if err != nil {
+			return nil, err
+		}
+		if goesLeft {
+			leaf = leaf.left
+		} else {
+			leaf = leaf.right
+		}
+	}
+	if leaf.ClassCounts == nil {
+		return nil, errors.New("No class distribution available in the leaf.")
+	}
+	return t.classProbabilities(leaf), nil
+}
+
+// Returns the (smoothed) class probabilities of the node of this tree, estimated from its class counts (see ClassifyProba).
+func (t *DecisionTree) classProbabilities(node *DecisionTree) map[Value]float64 {
+	probabilities := map[Value]float64{}
+	for class := range t.ClassCounts {
+		probabilities[class] = 0.0
+	}
+	total := 0.0
+	for class, count := range node.ClassCounts {
+		probabilities[class] = count
+		total += count
+	}
+
+	alpha, K := t.Options.LaplaceSmoothing, float64(len(probabilities))
+	for class, count := range probabilities {
+		probabilities[class] = (count + alpha) / (total + alpha*K)
+	}
+	return probabilities
+}
+
+// Returns true iff an observation with the given predictor value follows the split rule to the left subtree:
+// values smaller than the split value, or for categorical splits values belonging to the split category set.
+func (t *DecisionTree) goesLeft(predictorValue Value, splitValue Value) (bool, error) {
+	if categories, ok := splitValue.(CategorySet); ok {
+		return categories.Contains(predictorValue), nil
+	}
+	return _lt(predictorValue, splitValue)
+}
+
+// Returns the numeric prediction of a regression tree (one grown with a numeric LeafEstimator) for a new observation o.
+func (t *DecisionTree) Predict(o *Observation) (float64, error) {
+	val, err := t.Classify(o)
+	if err != nil {
+		return 0.0, err
+	}
+	return _float(val)
+}
+
 // Returns a tuple describing the split rule for this node.
 // Format for leaf nodes: <NO_PREDICTOR>,<NO_FLOAT>,<classification at node>
 // Format for internal nodes: <split predictor>,<split value>,<NO_CLASSIFICATION>
+// For categorical splits, the split value is the CategorySet of categories continuing to the left subtree.
 func (t *DecisionTree) GetRule() (predictor string, splitValue Value, classification Value) {
 	if t.IsLeaf() {
 		return NO_PREDICTOR, nil, t.Classification
@@ -7,6 +7,9 @@ import "os"
 import "fmt"
 import "encoding/csv"
 import "strconv"
+import "strings"
+import "context"
+import "math/rand"
 
 const TARGET_KEY = "__target"
 
@@ -97,11 +100,11 @@ func getSettings(name string, targetKey string) *Options {
 	giniStrategy := GiniPurity{}
 	switch name {
 	case "supergrow":
-		return &Options{2, 0.0, 10, giniStrategy, targetKey, &[]string{"feature1", "feature2", "feature3"}}
+		return &Options{MinSplitSize: 2, MaxSplitImpurity: 0.0, MaxDepth: 10, SplitStrategy: giniStrategy, TargetAttribute: targetKey, Predictors: &[]string{"feature1", "feature2", "feature3"}}
 	case "supergrow-nofeatures":
-		return &Options{2, 0.0, 10, giniStrategy, targetKey, &[]string{}}
+		return &Options{MinSplitSize: 2, MaxSplitImpurity: 0.0, MaxDepth: 10, SplitStrategy: giniStrategy, TargetAttribute: targetKey, Predictors: &[]string{}}
 	case "shallow":
-		return &Options{25, 0.15, 10, giniStrategy, targetKey, &[]string{"feature1", "feature2", "feature3"}}
+		return &Options{MinSplitSize: 25, MaxSplitImpurity: 0.15, MaxDepth: 10, SplitStrategy: giniStrategy, TargetAttribute: targetKey, Predictors: &[]string{"feature1", "feature2", "feature3"}}
 	}
 	return getSettings("supergrow", targetKey)
 }
@@ -199,11 +202,11 @@ func assertGini(tst *testing.T, testLabel string, gotGini float64, expectedGini
 }
 
 func Test_FindPredictorSplit(tst *testing.T) {
-	testBestPredictorSplitCase(tst, "{1,2,3}/f1", []string{}, "feature1", 3, 0.22222)
+	testBestPredictorSplitCase(tst, "{1,2,3}/f1", []string{}, "feature1", 3, 0.266667)
 
 	testBestPredictorSplitCase(tst, "{1,2,3}/f1", []string{}, "feature3", 3, 0.0)
-	testBestPredictorSplitCase(tst, "{1,2,3}/f2", []string{"feature1", "feature3"}, "feature2", 3, 0.222222)
-	testBestPredictorSplitCase(tst, "{1,2,3}/f1_2", []string{"feature2", "feature3"}, "feature1", 3, 0.222222)
+	testBestPredictorSplitCase(tst, "{1,2,3}/f2", []string{"feature1", "feature3"}, "feature2", 3, 0.266667)
+	testBestPredictorSplitCase(tst, "{1,2,3}/f1_2", []string{"feature2", "feature3"}, "feature1", 3, 0.266667)
 	testBestPredictorSplitCase(tst, "{1,2,3}/f3", []string{"feature1", "feature2"}, "feature3", 3, 0.0)
 }
 
@@ -222,7 +225,7 @@ func testBestPredictorSplitCase(tst *testing.T, testName string, excludeFeatures
 }
 
 func Test_FindBestSplit(tst *testing.T) {
-	three, zero, pt2 := 3, 0.0, 0.222222
+	three, zero, pt2 := 3, 0.0, 0.266667
 
 	testBestSplitCase(tst, "features={1,2,3}", "supergrow", []string{}, "feature3", &three, &zero)
 	testBestSplitCase(tst, "features={1,2}", "supergrow", []string{"feature3"}, "feature1", &three, &pt2)
@@ -421,14 +424,64 @@ func loadCsvDataset(path string, takeN int, skipN int) []*Observation {
 	return observations
 }
 
-func Test_CummulativeGoodCounts(tst *testing.T) {
-	g := GiniPurity{}
-	pgoods, _ := g.calculateCummulativeGoodSlice(prepareTestObservations([]string{}), "__target")
-	goods := *pgoods
-	if goods[0] == 0 && goods[1] == 1 && goods[2] == 1 && goods[3] == 1 && goods[4] == 2 && goods[5] == 3 && goods[len(goods)-1] == 3 {
-		tst.Log("Cummulative good target calculation passed.")
+func Test_CummulativeClassCounts(tst *testing.T) {
+	counts := GiniPurity{}.newAccumulator().(*classCounts)
+	ones, zeros := []float64{}, []float64{}
+	for _, obs := range prepareTestObservations([]string{}) {
+		counts.add((*obs)[TARGET_KEY], 1.0)
+		ones, zeros = append(ones, counts.counts[1.0]), append(zeros, counts.counts[0.0])
+	}
+
+	if fmt.Sprint(ones) == "[1 1 1 2 3]" && fmt.Sprint(zeros) == "[0 1 2 2 2]" && counts.total == 5.0 {
+		tst.Log("Cummulative class counts calculation passed.")
 	} else {
-		tst.Errorf("Cummulative good target calculation failed. Expected[0,1,1,1,2,3], but got [%d,%d,%d,%d,%d].", goods[0], goods[1], goods[2], goods[3], goods[4], goods[5])
+		tst.Errorf("Cummulative class counts calculation failed. Expected [1 1 1 2 3]/[0 1 2 2 2], but got %v/%v.", ones, zeros)
+	}
+}
+
+func prepareMulticlassObservations() []*Observation {
+	labels := []string{"red", "red", "green", "green", "green", "blue", "blue", "blue", "blue"}
+	observations := []*Observation{}
+	for i, label := range labels {
+		observations = append(observations, &Observation{"feature1": float64(i), TARGET_KEY: label})
+	}
+	return observations
+}
+
+func Test_MulticlassGini(tst *testing.T) {
+	observations := prepareMulticlassObservations()
+
+	pur, _ := GiniPurity{}.SlicePurity(observations, TARGET_KEY)
+	assertGini(tst, "multiclass", pur, 1-(4.0+9.0+16.0)/81.0)
+
+	t := new(DecisionTree)
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MinSplitSize = 1
+	settings.MaxSplitImpurity = 0.01
+	settings.Predictors = &[]string{"feature1"}
+	t.InitRoot(settings, observations)
+	t.Expand(true)
+
+	leaves := t.GetLeaves()
+	classes := []Value{}
+	for _, leaf := range leaves {
+		classes = append(classes, leaf.Classification)
+	}
+	if len(leaves) == 3 && fmt.Sprint(classes) == "[red green blue]" {
+		tst.Log("Multiclass expansion test passed.")
+	} else {
+		tst.Errorf("Multiclass expansion test failed. Expected leaves [red green blue], got %v.", classes)
+	}
+}
+
+func Test_GetMajorityVoteTie(tst *testing.T) {
+	t := new(DecisionTree)
+	t.Observations = prepareTestObservations([]string{})[:2]
+	t.initNode(getSettings("supergrow", "__target"), 0)
+	if val, _ := t.getMajorityVote(); val == 0.0 {
+		tst.Logf("Test majority vote tie passed.")
+	} else {
+		tst.Errorf("Test majority vote tie failed. Expected 0, got %v", val)
 	}
 }
 
@@ -516,3 +569,1007 @@ func verifyClassificationOutcome(tst *testing.T, t *DecisionTree, obs *Observati
 		}
 	}
 }
+
+func Test_Entropy(tst *testing.T) {
+	entropy := EntropyPurity{}
+	observations := prepareTestObservations([]string{})
+
+	pur1, _ := entropy.SlicePurity(observations[:1], "__target")
+	pur2, _ := entropy.SlicePurity(observations[:2], "__target")
+	pur3, _ := entropy.SlicePurity(observations[:3], "__target")
+	pur5, _ := entropy.SlicePurity(observations[:5], "__target")
+
+	assertGini(tst, "entropy [:1]", pur1, 0.0)
+	assertGini(tst, "entropy [:2]", pur2, 1.0)
+	assertGini(tst, "entropy [:3]", pur3, 0.918296)
+	assertGini(tst, "entropy [:5]", pur5, 0.970951)
+}
+
+func Test_EntropySplitPurity(tst *testing.T) {
+	cases := []struct {
+		strategy      AbstractPurityMetric
+		predictor     string
+		expectedIndex int
+		expectedValue float64
+	}{
+		{EntropyPurity{}, "feature1", 3, 0.550978},
+		{EntropyPurity{}, "feature3", 3, 0.0},
+		{GainRatioPurity{}, "feature1", 3, 0.551003},
+		{GainRatioPurity{}, "feature3", 3, 0.0},
+	}
+
+	for _, c := range cases {
+		t := new(DecisionTree)
+		settings := getSettings("supergrow", "__target")
+		settings.SplitStrategy = c.strategy
+		t.InitRoot(settings, prepareTestObservations([]string{}))
+
+		index, purity, err := t.bestSplitWithPredictor(c.predictor)
+		if err == nil && index != nil && *index == c.expectedIndex && math.Abs(*purity-c.expectedValue) < 0.001 {
+			tst.Logf("Split purity test (%T, %s) passed.", c.strategy, c.predictor)
+		} else {
+			tst.Errorf("Split purity test (%T, %s) failed. Expected (%d,%f), got (%v,%v,%v).", c.strategy, c.predictor, c.expectedIndex, c.expectedValue, index, purity, err)
+		}
+	}
+}
+
+func prepareRegressionObservations() []*Observation {
+	targets := []float64{10.0, 11.0, 9.0, 10.0, 100.0, 20.0, 21.0, 19.0, 20.0, 20.0}
+	observations := []*Observation{}
+	for i, y := range targets {
+		observations = append(observations, &Observation{"feature1": float64(i), "feature2": float64(i % 3), TARGET_KEY: y})
+	}
+	return observations
+}
+
+func getRegressionSettings(strategy AbstractPurityMetric, estimator AbstractLeafEstimator) *Options {
+	return &Options{MinSplitSize: 2, MaxSplitImpurity: 0.0, MaxDepth: 1, SplitStrategy: strategy, TargetAttribute: TARGET_KEY,
+		Predictors: &[]string{"feature1", "feature2"}, LeafEstimator: estimator}
+}
+
+func Test_RegressionPurity(tst *testing.T) {
+	observations := prepareRegressionObservations()
+
+	mse, _ := MSEPurity{}.SlicePurity(observations[:4], TARGET_KEY)
+	mae, _ := MAEPurity{}.SlicePurity(observations[:5], TARGET_KEY)
+	assertGini(tst, "mse [:4]", mse, 0.5)
+	assertGini(tst, "mae [:5]", mae, 18.4)
+}
+
+func Test_RegressionTree(tst *testing.T) {
+	cases := []struct {
+		strategy      AbstractPurityMetric
+		estimator     AbstractLeafEstimator
+		expectedIndex int
+		expectedLeft  float64
+	}{
+		{MSEPurity{}, MeanEstimator{}, 4, 10.0},
+		{MAEPurity{}, MedianEstimator{}, 4, 10.0},
+	}
+
+	for _, c := range cases {
+		t := new(DecisionTree)
+		t.InitRoot(getRegressionSettings(c.strategy, c.estimator), prepareRegressionObservations())
+		t.Expand(true)
+
+		got, err := t.Predict(&Observation{"feature1": 0.5, "feature2": 0.0})
+		if err == nil && len(t.left.Observations) == c.expectedIndex && math.Abs(got-c.expectedLeft) < 0.001 {
+			tst.Logf("Regression tree test (%T) passed.", c.strategy)
+		} else {
+			tst.Errorf("Regression tree test (%T) failed. Expected split at %d predicting %f, got split at %d predicting %f (%v).",
+				c.strategy, c.expectedIndex, c.expectedLeft, len(t.left.Observations), got, err)
+		}
+	}
+}
+
+func prepareCategoricalObservations() []*Observation {
+	countries := []string{"CZ", "SK", "AT", "DE", "CZ", "DE", "AT", "SK", "CZ", "DE"}
+	targets := []float64{1.0, 0.0, 1.0, 0.0, 1.0, 0.0, 1.0, 0.0, 1.0, 0.0}
+	observations := []*Observation{}
+	for i, country := range countries {
+		observations = append(observations, &Observation{"country": country, "feature1": float64(i), TARGET_KEY: targets[i]})
+	}
+	return observations
+}
+
+func Test_CategoricalSplit(tst *testing.T) {
+	t := new(DecisionTree)
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.Predictors = &[]string{"country", "feature1"}
+	settings.CategoricalPredictors = &[]string{"country"}
+	settings.MaxSplitImpurity = 0.01
+	t.InitRoot(settings, prepareCategoricalObservations())
+	t.Expand(true)
+
+	predictor, val, _ := t.GetRule()
+	if categories, ok := val.(CategorySet); !ok || predictor != "country" || categories.String() != "{AT,CZ}" {
+		tst.Errorf("Categorical split test failed. Expected rule 'country in {AT,CZ}', got (%s,%v).", predictor, val)
+	}
+
+	verifyClassificationOutcome(tst, t, &Observation{"country": "SK", "feature1": 100.0}, 0.0)
+	verifyClassificationOutcome(tst, t, &Observation{"country": "AT", "feature1": 100.0}, 1.0)
+	verifyClassificationOutcome(tst, t, &Observation{"country": "HU", "feature1": 100.0}, 0.0)
+
+	if model := t.GetSerializedModel(); strings.Contains(model, `"splitIn":["AT","CZ"]`) {
+		tst.Log("Categorical split serialization test passed.")
+	} else {
+		tst.Errorf("Categorical split serialization test failed, got %s.", model)
+	}
+}
+
+func prepareMissingValueObservations() []*Observation {
+	observations := []*Observation{}
+	for i := 0; i < 20; i++ {
+		target := 0.0
+		if i >= 10 {
+			target = 1.0
+		}
+		obs := Observation{"feature1": float64(i), "feature2": float64(100 - i), "feature3": float64(i % 2), TARGET_KEY: target}
+		if noisy, ok := map[int]float64{9: 70.0, 10: 99.5}[i]; ok { // feature2 is a noisy copy of feature1
+			obs["feature2"] = noisy
+		}
+		if i%4 == 0 {
+			delete(obs, "feature1")
+		}
+		observations = append(observations, &obs)
+	}
+	return observations
+}
+
+func Test_SurrogateSplits(tst *testing.T) {
+	t := new(DecisionTree)
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MaxDepth = 1
+	settings.MaxSurrogates = 2
+	settings.Predictors = &[]string{"feature1", "feature2"}
+	if err := t.InitRoot(settings, prepareMissingValueObservations()); err != nil {
+		tst.Fatalf("Surrogate split test failed to initialize: %s", err.Error())
+	}
+	t.Expand(true)
+
+	if *t.SplitPredictor != "feature1" || len(t.Surrogates) != 1 || t.Surrogates[0].Predictor != "feature2" ||
+		!t.Surrogates[0].Inverted || math.Abs(t.Surrogates[0].Agreement-13.0/15.0) > 0.001 {
+		tst.Errorf("Surrogate split test failed. Expected split on feature1 with inverted surrogate feature2, got %s with %v.", *t.SplitPredictor, t.Surrogates)
+	}
+	// observations missing feature1 follow the surrogate rule feature2 >= 93 to the left (#8 having feature2=92 does not)
+	if len(t.left.Observations) != 9 || len(t.right.Observations) != 11 {
+		tst.Errorf("Surrogate split test failed. Expected to split into (9,11) nodes, got (%d,%d).", len(t.left.Observations), len(t.right.Observations))
+	}
+
+	verifyClassificationOutcome(tst, t, &Observation{"feature2": 99.0}, 0.0)
+	verifyClassificationOutcome(tst, t, &Observation{"feature2": 82.0}, 1.0)
+	verifyClassificationOutcome(tst, t, &Observation{"feature3": 1.0}, 1.0)
+}
+
+func Test_CostComplexityPruning(tst *testing.T) {
+	t := new(DecisionTree)
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MinSplitSize = 1
+	settings.MaxSplitImpurity = 0.01
+	t.InitRoot(settings, prepareMissingValueObservations())
+	t.Expand(true)
+
+	path, err := t.CostComplexityPath()
+	if err != nil || len(path) < 2 || path[0].Alpha != 0.0 || path[0].Leaves != len(t.GetLeaves()) || path[len(path)-1].Leaves != 1 {
+		tst.Fatalf("Cost-complexity path test failed, got %v (%v).", path, err)
+	}
+	for i := 1; i < len(path); i++ {
+		if path[i].Alpha < path[i-1].Alpha || path[i].Leaves >= path[i-1].Leaves || path[i].Risk < path[i-1].Risk {
+			tst.Errorf("Cost-complexity path test failed, the sequence is not monotonous: %v.", path)
+		}
+	}
+	if math.Abs(path[len(path)-1].Risk-0.5) > 0.001 {
+		tst.Errorf("Cost-complexity path test failed, expected the root risk 0.5, got %f.", path[len(path)-1].Risk)
+	}
+
+	alpha, err := t.CrossValidateAlpha(4, 42)
+	if err != nil || alpha < 0.0 {
+		tst.Errorf("Cross-validation of alpha failed, got %f (%v).", alpha, err)
+	}
+
+	t.Prune(path[len(path)-2].Alpha)
+	if len(t.GetLeaves()) != path[len(path)-2].Leaves {
+		tst.Errorf("Pruning test failed. Expected %d leaves, got %d.", path[len(path)-2].Leaves, len(t.GetLeaves()))
+	}
+
+	t.Prune(path[len(path)-1].Alpha)
+	if !t.IsLeaf() || t.Classification != 0.0 {
+		tst.Errorf("Pruning test failed. Expected the tree collapsed into a leaf voting 0, got %v.", t.Classification)
+	}
+}
+
+func Test_PruneWithValidation(tst *testing.T) {
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MinSplitSize = 1
+	settings.MaxSplitImpurity = 0.01
+
+	// Case 1: pruning never decreases the validation accuracy
+	t := new(DecisionTree)
+	t.InitRoot(settings, prepareMissingValueObservations())
+	t.Expand(true)
+	validation := prepareMissingValueObservations()
+	for i, obs := range validation {
+		(*obs)["feature1"] = float64(i)
+	}
+
+	leavesBefore := len(t.GetLeaves())
+	errorsBefore, _ := t.testError(validation)
+	if err := t.PruneWithValidation(validation); err != nil {
+		tst.Fatalf("Reduced-error pruning failed: %s", err.Error())
+	}
+	errorsAfter, _ := t.testError(validation)
+	if errorsAfter <= errorsBefore && len(t.GetLeaves()) <= leavesBefore {
+		tst.Logf("Reduced-error pruning test 1 passed (%d -> %d leaves).", leavesBefore, len(t.GetLeaves()))
+	} else {
+		tst.Errorf("Reduced-error pruning test 1 failed (%f -> %f errors, %d -> %d leaves).", errorsBefore, errorsAfter, leavesBefore, len(t.GetLeaves()))
+	}
+
+	// Case 2: validation observations of a single class collapse the tree into a leaf voting that class
+	t = new(DecisionTree)
+	t.InitRoot(settings, prepareMissingValueObservations())
+	t.Expand(true)
+	t.PruneWithValidation(validation[:10])
+	if t.IsLeaf() && t.Classification == 0.0 {
+		tst.Log("Reduced-error pruning test 2 passed.")
+	} else {
+		tst.Errorf("Reduced-error pruning test 2 failed. Expected a single leaf voting 0, got %d leaves.", len(t.GetLeaves()))
+	}
+}
+
+func Test_LoadSerializedModel(tst *testing.T) {
+	// Case 1: numeric splits with float64 classes
+	t := new(DecisionTree)
+	t.InitRoot(getSettings("supergrow", TARGET_KEY), prepareTestObservations([]string{}))
+	t.Expand(true)
+	verifyModelRoundTrip(tst, t, prepareTestObservations([]string{}))
+
+	// Case 2: categorical splits, surrogates and int/string classes
+	observations := prepareCategoricalObservations()
+	for i, obs := range observations {
+		(*obs)["feature2"] = i % 3
+		(*obs)[TARGET_KEY] = int((*obs)[TARGET_KEY].(float64))
+		if i%2 == 0 {
+			(*obs)[TARGET_KEY] = []string{"no", "yes"}[(*obs)[TARGET_KEY].(int)]
+		}
+	}
+	t = new(DecisionTree)
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.Predictors = &[]string{"country", "feature1", "feature2"}
+	settings.CategoricalPredictors = &[]string{"country", "feature2"}
+	settings.MaxSurrogates = 2
+	settings.MinSplitSize = 1
+	t.InitRoot(settings, observations)
+	t.Expand(true)
+	verifyModelRoundTrip(tst, t, append(observations, &Observation{"feature2": 1}, &Observation{}))
+
+	// Case 3: legacy models without type information
+	legacy, err := LoadSerializedModel([]byte(`{"splitOn":"x","splitValue":2,"ifLeq":{"classification":0},"ifGt":{"classification":"high"}}`))
+	if err != nil || legacy.Options.TargetAttribute != "" || len(*legacy.Options.Predictors) != 1 {
+		tst.Fatalf("Loading a legacy model failed (%v).", err)
+	}
+	verifyClassificationOutcome(tst, legacy, &Observation{"x": 1.5}, 0.0)
+	verifyClassificationOutcome(tst, legacy, &Observation{"x": 2.5}, "high")
+}
+
+func verifyModelRoundTrip(tst *testing.T, t *DecisionTree, observations []*Observation) {
+	model := t.GetSerializedModel()
+	loaded, err := LoadSerializedModel([]byte(model))
+	if err != nil {
+		tst.Fatalf("Loading the serialized model failed: %s", err.Error())
+	}
+
+	if reserialized := loaded.GetSerializedModel(); reserialized != model {
+		tst.Errorf("Serialized model round trip failed. Expected %s, got %s.", model, reserialized)
+	}
+	if loaded.Options.TargetAttribute != t.Options.TargetAttribute || fmt.Sprint(*loaded.Options.Predictors) != fmt.Sprint(*t.Options.Predictors) {
+		tst.Errorf("Serialized model round trip failed to recover the options, got %v.", loaded.Options)
+	}
+	for _, obs := range observations {
+		expected, _ := t.Classify(obs)
+		got, _ := loaded.Classify(obs)
+		if expected != got {
+			tst.Errorf("Serialized model round trip failed. Expected classification %v (%T), got %v (%T).", expected, expected, got, got)
+		}
+	}
+}
+
+func Test_ClassifyProba(tst *testing.T) {
+	t := new(DecisionTree)
+	t.InitRoot(getSettings("supergrow", TARGET_KEY), prepareTestObservations([]string{}))
+	t.Expand(true)
+	obs := &Observation{"feature1": 4.7, "feature2": 4.7, "feature3": 4.7}
+
+	probabilities, err := t.ClassifyProba(obs)
+	if err != nil || len(probabilities) != 2 || probabilities[1.0] != 1.0 || probabilities[0.0] != 0.0 {
+		tst.Errorf("Class probability test 1 failed. Expected {0:0, 1:1}, got %v (%v).", probabilities, err)
+	}
+
+	t.Options.LaplaceSmoothing = 1.0
+	probabilities, _ = t.ClassifyProba(obs)
+	if math.Abs(probabilities[1.0]-0.8) > 0.001 || math.Abs(probabilities[0.0]-0.2) > 0.001 {
+		tst.Errorf("Class probability test 2 failed. Expected {0:0.2, 1:0.8}, got %v.", probabilities)
+	}
+
+	loaded, _ := LoadSerializedModel([]byte(t.GetSerializedModel()))
+	loaded.Options.LaplaceSmoothing = 1.0
+	if loadedProbabilities, _ := loaded.ClassifyProba(obs); fmt.Sprint(loadedProbabilities) != fmt.Sprint(probabilities) {
+		tst.Errorf("Class probability test 3 failed. Expected %v after loading the model, got %v.", probabilities, loadedProbabilities)
+	}
+}
+
+func Test_WeightedRegression(tst *testing.T) {
+	// integer weights must act as duplicated observations
+	observations, duplicated := prepareRegressionObservations(), []*Observation{}
+	for i, obs := range observations {
+		(*obs)["__weight"] = float64(i%3 + 1)
+		for k := 0; k <= i%3; k++ {
+			duplicated = append(duplicated, obs)
+		}
+	}
+
+	for _, strategy := range []AbstractPurityMetric{MSEPurity{}, MAEPurity{}} {
+		weighted, plain := new(DecisionTree), new(DecisionTree)
+		settings := getRegressionSettings(strategy, MedianEstimator{})
+		weighted.InitRoot(&Options{MinSplitSize: 2, MaxSplitImpurity: 0.0, MaxDepth: 1, SplitStrategy: strategy, TargetAttribute: TARGET_KEY,
+			Predictors: settings.Predictors, LeafEstimator: MedianEstimator{}, WeightAttribute: "__weight"}, observations)
+		plain.InitRoot(settings, duplicated)
+
+		impurity, _ := weighted.Impurity()
+		expected, _ := strategy.SlicePurity(duplicated, TARGET_KEY)
+		assertGini(tst, "weighted slice", impurity, expected)
+
+		_, purityW, err := strategy.SplitPurity("feature1", TARGET_KEY, weighted)
+		_, purityD, _ := strategy.SplitPurity("feature1", TARGET_KEY, plain)
+		if err != nil || purityW == nil || purityD == nil {
+			tst.Fatalf("Weighted split purity test failed (%v).", err)
+		}
+		assertGini(tst, "weighted split", *purityW, *purityD)
+
+		estimateW, _ := weighted.getLeafValue()
+		estimateD, _ := plain.getLeafValue()
+		if estimateW != estimateD {
+			tst.Errorf("Weighted leaf estimate test failed. Expected %v, got %v.", estimateD, estimateW)
+		}
+	}
+}
+
+func Test_WeightedMinSplitSize(tst *testing.T) {
+	observations := prepareTestObservations([]string{})[2:4] // targets 0 and 1
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MinSplitSize = 3
+
+	t := new(DecisionTree)
+	t.InitRoot(settings, observations)
+	if growable, _ := t.isGrowable(); growable {
+		tst.Errorf("Unweighted node of 2 observations should not be growable with MinSplitSize 3.")
+	}
+
+	for _, obs := range observations {
+		(*obs)["__weight"] = 3.0
+	}
+	weightedSettings := *settings
+	weightedSettings.WeightAttribute = "__weight"
+	t = new(DecisionTree)
+	t.InitRoot(&weightedSettings, observations)
+	t.Expand(true)
+	if t.IsLeaf() {
+		tst.Errorf("Node of 2 observations weighted 3 each should be split with MinSplitSize 3.")
+	}
+}
+
+func Test_WeightedGini(tst *testing.T) {
+	observations := prepareTestObservations([]string{})
+	for i, obs := range observations {
+		(*obs)["__weight"] = float64(i + 1) // targets 1,0,0,1,1 weighted 1,2,3,4,5
+	}
+
+	t := new(DecisionTree)
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.WeightAttribute = "__weight"
+	t.InitRoot(settings, observations)
+
+	impurity, _ := t.Impurity()
+	assertGini(tst, "weighted", impurity, 1-(100.0+25.0)/225.0)
+	if vote, _ := t.getMajorityVote(); vote != 1.0 {
+		tst.Errorf("Weighted majority vote test failed. Expected 1, got %v.", vote)
+	}
+
+	t.Observations = observations[:3]
+	t.impurity = nil
+	if vote, _ := t.getMajorityVote(); vote != 0.0 {
+		tst.Errorf("Weighted majority vote test failed. Expected 0, got %v.", vote)
+	}
+	impurity, _ = t.Impurity()
+	assertGini(tst, "weighted [:3]", impurity, 1-(1.0+25.0)/36.0)
+}
+
+func Test_WeightValidation(tst *testing.T) {
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.WeightAttribute = "__weight"
+	for name, weight := range map[string]Value{"missing": nil, "non-numeric": "heavy", "negative": -1.0} {
+		observations := prepareTestObservations([]string{})
+		for _, obs := range observations {
+			(*obs)["__weight"] = 1.0
+		}
+		(*observations[2])["__weight"] = weight
+		if err := new(DecisionTree).InitRoot(settings, observations); err == nil {
+			tst.Errorf("Weight validation test failed, %s weight accepted.", name)
+		}
+	}
+
+	observations := prepareTestObservations([]string{})
+	for i, obs := range observations {
+		(*obs)["__weight"] = float64(i + 1)
+	}
+	t := new(DecisionTree)
+	if err := t.InitRoot(settings, observations); err != nil {
+		tst.Fatalf("Weight validation test failed: %s", err.Error())
+	}
+	impurity, _ := t.Impurity()
+	slicePurity, _ := t.SlicePurity(observations)
+	assertGini(tst, "weighted slice purity", slicePurity, impurity)
+}
+
+func prepareImbalancedObservations() []*Observation {
+	observations := []*Observation{}
+	positives := 0
+	for i := 0; i < 100; i++ {
+		target := 0.0
+		if i%10 >= 8 && positives < 5 { // 5 positives among the 20 observations with feature1 >= 8
+			target, positives = 1.0, positives+1
+		}
+		observations = append(observations, &Observation{"feature1": float64(i % 10), TARGET_KEY: target})
+	}
+	return observations
+}
+
+func Test_ClassWeightsAndLossMatrix(tst *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(*Options)
+		expected float64
+	}{
+		{"unweighted", func(o *Options) {}, 0.0},
+		{"class weights", func(o *Options) { o.ClassWeights = map[Value]float64{1.0: 4.0} }, 1.0},
+		{"balanced", func(o *Options) { o.BalancedClassWeights = true }, 1.0},
+		{"loss matrix", func(o *Options) { o.LossMatrix = LossMatrix{1.0: {0.0: 20.0}} }, 1.0},
+	}
+
+	for _, c := range cases {
+		settings := &Options{MinSplitSize: 2, MaxSplitImpurity: 0.0, MaxDepth: 1, SplitStrategy: GiniPurity{}, TargetAttribute: TARGET_KEY,
+			Predictors: &[]string{"feature1"}}
+		c.modify(settings)
+
+		t := new(DecisionTree)
+		t.InitRoot(settings, prepareImbalancedObservations())
+		t.Expand(true)
+		if got, _ := t.Classify(&Observation{"feature1": 9.0}); got != c.expected {
+			tst.Errorf("Class weights test (%s) failed. Expected %v, got %v.", c.name, c.expected, got)
+		} else {
+			tst.Logf("Class weights test (%s) passed.", c.name)
+		}
+	}
+
+	// class weights change the impurities and the leaf values, not the class counts nor the sizes of the nodes
+	settings := &Options{MinSplitSize: 2, MaxSplitImpurity: 0.0, MaxDepth: 1, SplitStrategy: GiniPurity{}, TargetAttribute: TARGET_KEY,
+		Predictors: &[]string{"feature1"}, ClassWeights: map[Value]float64{1.0: 4.0}, MinLeafSize: 21}
+	t := new(DecisionTree)
+	t.InitRoot(settings, prepareImbalancedObservations())
+	t.Expand(true)
+	if t.ClassCounts[0.0] != 95.0 || t.ClassCounts[1.0] != 5.0 {
+		tst.Errorf("Class weights test (sample counts) failed, got %v.", t.ClassCounts)
+	}
+	for _, leaf := range t.GetLeaves() {
+		if len(leaf.Observations) < settings.MinLeafSize {
+			tst.Errorf("Class weights test (MinLeafSize) failed, got a leaf of %d observations.", len(leaf.Observations))
+		}
+	}
+
+	// without explicit costs, the cost-weighted gini is the ordinary gini
+	counts := map[Value]float64{0.0: 3, 1.0: 1, 2.0: 1}
+	assertGini(tst, "0/1 loss", LossMatrix{}.gini(counts, 5), gini(counts, 5))
+}
+
+// Observations with target 1 iff feature1 + feature2 > 10, feature3 being noise; a fraction of the labels is flipped.
+func prepareSyntheticObservations(n int, noise float64, seed int64) []*Observation {
+	random := rand.New(rand.NewSource(seed))
+	observations := make([]*Observation, n)
+	for i := range observations {
+		obs := Observation{"feature1": random.Float64() * 10, "feature2": random.Float64() * 10, "feature3": random.Float64() * 10}
+		target := 0.0
+		if obs["feature1"].(float64)+obs["feature2"].(float64) > 10 {
+			target = 1.0
+		}
+		if random.Float64() < noise {
+			target = 1.0 - target
+		}
+		obs[TARGET_KEY] = target
+		observations[i] = &obs
+	}
+	return observations
+}
+
+func Test_RandomForest(tst *testing.T) {
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MaxDepth = 6
+
+	forest := &RandomForest{NumTrees: 25, Seed: 7, Options: settings}
+	if err := forest.Grow(prepareSyntheticObservations(300, 0.05, 1)); err != nil {
+		tst.Fatalf("Growing the random forest failed: %s", err.Error())
+	}
+
+	oob, err := forest.OOBError()
+	if err != nil || oob > 0.2 {
+		tst.Errorf("Random forest OOB error test failed (%f, %v).", oob, err)
+	} else {
+		tst.Logf("Random forest OOB error is %f.", oob)
+	}
+
+	testSet := prepareSyntheticObservations(200, 0.0, 2)
+	successes := 0
+	for _, obs := range testSet {
+		if got, _ := forest.Classify(obs); got == (*obs)[TARGET_KEY] {
+			successes++
+		}
+	}
+	if successes < 170 {
+		tst.Errorf("Random forest success rate is %d/200.", successes)
+	}
+
+	probabilities, _ := forest.ClassifyProba(&Observation{"feature1": 9.0, "feature2": 9.0, "feature3": 1.0})
+	if probabilities[1.0] < 0.9 || probabilities[1.0]+probabilities[0.0] < 0.999 {
+		tst.Errorf("Random forest probability test failed, got %v.", probabilities)
+	}
+
+	// the trees vote with their cost-sensitive classifications
+	costly := &Options{MinSplitSize: 2, MaxSplitImpurity: 0.0, MaxDepth: 1, SplitStrategy: GiniPurity{}, TargetAttribute: TARGET_KEY,
+		Predictors: &[]string{"feature1"}, LossMatrix: LossMatrix{1.0: {0.0: 20.0}}}
+	costSensitive := &RandomForest{NumTrees: 15, Seed: 5, Options: costly}
+	costSensitive.Grow(prepareImbalancedObservations())
+	if got, err := costSensitive.Classify(&Observation{"feature1": 9.0}); got != 1.0 || err != nil {
+		tst.Errorf("Random forest loss matrix test failed, got %v (%v).", got, err)
+	}
+
+	// the same seed grows the same forest
+	again := &RandomForest{NumTrees: 25, Seed: 7, Options: settings}
+	again.Grow(prepareSyntheticObservations(300, 0.05, 1))
+	if oobAgain, _ := again.OOBError(); oobAgain != oob {
+		tst.Errorf("Random forest reproducibility test failed (%f vs %f).", oob, oobAgain)
+	}
+}
+
+func Test_RandomForestRegression(tst *testing.T) {
+	forest := &RandomForest{NumTrees: 10, Seed: 3, Options: getRegressionSettings(MSEPurity{}, MeanEstimator{})}
+	forest.Options.MaxDepth = 3
+	forest.Grow(prepareRegressionObservations())
+
+	got, err := forest.Classify(&Observation{"feature1": 1.0, "feature2": 1.0})
+	if prediction, _ := got.(float64); err != nil || prediction < 9.0 || prediction > 40.0 {
+		tst.Errorf("Random forest regression test failed, got %v (%v).", got, err)
+	}
+	if _, err := forest.OOBError(); err != nil {
+		tst.Errorf("Random forest regression OOB error failed: %s", err.Error())
+	}
+}
+
+func Test_GradientBoosting(tst *testing.T) {
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MaxDepth = 3
+	settings.MinSplitSize = 5
+
+	boosting := &GradientBoostingClassifier{NumRounds: 200, LearningRate: 0.3, Subsample: 0.8, EarlyStoppingRounds: 10, Seed: 1, Options: settings}
+	err := boosting.Fit(prepareSyntheticObservations(300, 0.05, 1), prepareSyntheticObservations(100, 0.05, 3))
+	if err != nil {
+		tst.Fatalf("Fitting the gradient boosting failed: %s", err.Error())
+	}
+	if len(boosting.Trees) == 0 || len(boosting.Trees) == 200 {
+		tst.Errorf("Gradient boosting early stopping test failed, got %d trees.", len(boosting.Trees))
+	}
+
+	successes := 0
+	for _, obs := range prepareSyntheticObservations(200, 0.0, 2) {
+		if got, _ := boosting.Classify(obs); got == (*obs)[TARGET_KEY] {
+			successes++
+		}
+	}
+	if successes < 170 {
+		tst.Errorf("Gradient boosting success rate is %d/200.", successes)
+	} else {
+		tst.Logf("Gradient boosting success rate is %d/200 (%d trees).", successes, len(boosting.Trees))
+	}
+
+	high, _ := boosting.PredictProba(&Observation{"feature1": 9.0, "feature2": 9.0, "feature3": 1.0})
+	low, _ := boosting.PredictProba(&Observation{"feature1": 1.0, "feature2": 1.0, "feature3": 1.0})
+	if high < 0.8 || low > 0.2 || boosting.PositiveClass != 1.0 {
+		tst.Errorf("Gradient boosting probability test failed, got %f and %f.", high, low)
+	}
+
+	multiclass := &GradientBoostingClassifier{NumRounds: 5, Options: settings}
+	if err := multiclass.Fit(prepareMulticlassObservations(), nil); err == nil {
+		tst.Errorf("Gradient boosting should reject non-binary targets.")
+	}
+}
+
+func Test_GradientBoostingWeighted(tst *testing.T) {
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MaxDepth = 3
+	settings.MinSplitSize = 5
+
+	// integer weights are expected to act as duplicated observations
+	observations, duplicated := prepareSyntheticObservations(100, 0.05, 1), []*Observation{}
+	for i, obs := range observations {
+		(*obs)["__weight"] = float64(1 + i%3)
+		for k := 0; k <= i%3; k++ {
+			duplicated = append(duplicated, obs)
+		}
+	}
+	weightedSettings := *settings
+	weightedSettings.WeightAttribute = "__weight"
+	weighted := &GradientBoostingClassifier{NumRounds: 10, LearningRate: 0.3, Options: &weightedSettings}
+	plain := &GradientBoostingClassifier{NumRounds: 10, LearningRate: 0.3, Options: settings}
+	if err := weighted.Fit(observations, nil); err != nil {
+		tst.Fatalf("Fitting the weighted gradient boosting failed: %s", err.Error())
+	}
+	if err := plain.Fit(duplicated, nil); err != nil {
+		tst.Fatalf("Fitting the gradient boosting failed: %s", err.Error())
+	}
+	if math.Abs(weighted.InitialScore-plain.InitialScore) > 1e-9 {
+		tst.Errorf("Weighted gradient boosting prior test failed, got %f instead of %f.", weighted.InitialScore, plain.InitialScore)
+	}
+	for _, obs := range prepareSyntheticObservations(50, 0.0, 2) {
+		got, _ := weighted.PredictProba(obs)
+		expected, _ := plain.PredictProba(obs)
+		if math.Abs(got-expected) > 1e-9 {
+			tst.Errorf("Weighted gradient boosting test failed, got %f instead of %f.", got, expected)
+			break
+		}
+	}
+
+	(*observations[0])["__weight"] = -1.0
+	if err := weighted.Fit(observations, nil); err == nil {
+		tst.Errorf("Gradient boosting should reject negative weights.")
+	}
+}
+
+func Test_AdaBoost(tst *testing.T) {
+	settings := getSettings("supergrow", TARGET_KEY)
+	ensemble := &AdaBoost{NumRounds: 60, Options: settings}
+	training := prepareSyntheticObservations(300, 0.0, 1)
+	if err := ensemble.Fit(training); err != nil {
+		tst.Fatalf("Fitting AdaBoost failed: %s", err.Error())
+	}
+	if len(ensemble.Stumps) < 2 || len(ensemble.Stumps) != len(ensemble.Alphas) {
+		tst.Errorf("AdaBoost test failed, got %d stumps.", len(ensemble.Stumps))
+	}
+	if settings.WeightAttribute != "" || len(*training[0]) != len(*prepareSyntheticObservations(1, 0.0, 1)[0]) {
+		tst.Errorf("AdaBoost must not change the grow options nor the observations.")
+	}
+
+	successes := 0
+	for _, obs := range prepareSyntheticObservations(200, 0.0, 2) {
+		if got, _ := ensemble.Classify(obs); got == (*obs)[TARGET_KEY] {
+			successes++
+		}
+	}
+	if successes < 175 {
+		tst.Errorf("AdaBoost success rate is %d/200.", successes)
+	} else {
+		tst.Logf("AdaBoost success rate is %d/200 (%d stumps).", successes, len(ensemble.Stumps))
+	}
+
+	multiclass := &AdaBoost{NumRounds: 10, Options: settings}
+	settings.Predictors = &[]string{"feature1"}
+	multiclass.Fit(prepareMulticlassObservations())
+	for feature, expected := range map[float64]string{0.0: "red", 3.0: "green", 8.0: "blue"} {
+		if got, _ := multiclass.Classify(&Observation{"feature1": feature}); got != expected {
+			tst.Errorf("AdaBoost multiclass test failed. Expected %s, got %v.", expected, got)
+		}
+	}
+
+	weightless := prepareMulticlassObservations()
+	for _, obs := range weightless {
+		(*obs)["__weight"] = 0.0
+	}
+	weightlessSettings := *settings
+	weightlessSettings.WeightAttribute = "__weight"
+	if err := (&AdaBoost{NumRounds: 10, Options: &weightlessSettings}).Fit(weightless); err == nil {
+		tst.Errorf("AdaBoost should reject observations without weight.")
+	}
+}
+
+func Test_StopRules(tst *testing.T) {
+	grow := func(modify func(*Options)) *DecisionTree {
+		settings := getSettings("supergrow", TARGET_KEY)
+		settings.MaxDepth = 20
+		modify(settings)
+		t := new(DecisionTree)
+		t.InitRoot(settings, prepareSyntheticObservations(200, 0.2, 3))
+		t.Expand(true)
+		return t
+	}
+
+	full := grow(func(o *Options) {})
+	tst.Logf("Fully grown tree has %d leaves.", len(full.GetLeaves()))
+
+	for _, leaf := range grow(func(o *Options) { o.MinLeafSize = 10 }).GetLeaves() {
+		if len(leaf.Observations) < 10 {
+			tst.Errorf("MinLeafSize test failed, got a leaf with %d observations.", len(leaf.Observations))
+		}
+	}
+
+	if leaves := len(grow(func(o *Options) { o.MaxLeafNodes = 5 }).GetLeaves()); leaves != 5 {
+		tst.Errorf("MaxLeafNodes test failed. Expected 5 leaves, got %d.", leaves)
+	}
+
+	limited := grow(func(o *Options) { o.MinImpurityDecrease = 0.01 })
+	if len(limited.GetLeaves()) >= len(full.GetLeaves()) {
+		tst.Errorf("MinImpurityDecrease test failed, got %d leaves.", len(limited.GetLeaves()))
+	}
+	var verify func(node *DecisionTree)
+	verify = func(node *DecisionTree) {
+		if node.IsLeaf() {
+			return
+		}
+		purity := (node.left.totalWeight(node.left.Observations)*(*node.left.impurity) +
+			node.right.totalWeight(node.right.Observations)*(*node.right.impurity)) / node.totalWeight(node.Observations)
+		if decrease, _ := node.impurityDecrease(purity); decrease < 0.01-1e-9 {
+			tst.Errorf("MinImpurityDecrease test failed, got a split decreasing the impurity by %f.", decrease)
+		}
+		verify(node.left)
+		verify(node.right)
+	}
+	verify(limited)
+}
+
+func Test_ExpandBestFirst(tst *testing.T) {
+	risk := func(t *DecisionTree) float64 {
+		leafRisks := map[*DecisionTree]float64{}
+		t.collectLeafRisks(leafRisks, 1.0)
+		risk, _, _, _ := t.weakestLinks(leafRisks, map[*DecisionTree]bool{})
+		return risk
+	}
+
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MaxDepth, settings.MaxLeafNodes = 20, 6
+
+	depthFirst, bestFirst := new(DecisionTree), new(DecisionTree)
+	depthFirst.InitRoot(settings, prepareSyntheticObservations(300, 0.05, 4))
+	depthFirst.Expand(true)
+	bestFirst.InitRoot(settings, prepareSyntheticObservations(300, 0.05, 4))
+	if err := bestFirst.ExpandBestFirst(); err != nil {
+		tst.Fatalf("Best-first growth failed: %s", err.Error())
+	}
+
+	if leaves := len(bestFirst.GetLeaves()); leaves != 6 {
+		tst.Errorf("Best-first growth test failed. Expected 6 leaves, got %d.", leaves)
+	}
+	for _, leaf := range bestFirst.GetLeaves() {
+		if leaf.Classification == nil {
+			tst.Errorf("Best-first growth test failed, got a leaf without classification.")
+		}
+	}
+	if risk(bestFirst) > risk(depthFirst) {
+		tst.Errorf("Best-first growth test failed, misclassified %.0f observations (depth-first %.0f).", risk(bestFirst), risk(depthFirst))
+	} else {
+		tst.Logf("Best-first tree misclassifies %.0f observations (depth-first %.0f).", risk(bestFirst), risk(depthFirst))
+	}
+}
+
+func Test_ConcurrentSplitSearch(tst *testing.T) {
+	grow := func(workers int) *DecisionTree {
+		observations := prepareSyntheticObservations(300, 0.1, 5)
+		for _, obs := range observations {
+			(*obs)["feature4"] = (*obs)["feature2"] // ties must go to the predictor listed first
+		}
+		settings := getSettings("supergrow", TARGET_KEY)
+		settings.Predictors = &[]string{"feature1", "feature2", "feature3", "feature4"}
+		settings.SplitWorkers = workers
+
+		t := new(DecisionTree)
+		t.InitRoot(settings, observations)
+		t.Expand(true)
+		return t
+	}
+
+	sequential, concurrent := grow(0).GetSerializedModel(), grow(3).GetSerializedModel()
+	if sequential != concurrent {
+		tst.Errorf("Concurrent split search test failed, the trees differ:\n%s\n%s", sequential, concurrent)
+	}
+	for _, predictor := range grow(3).GetUsedPredictors() {
+		if predictor == "feature4" {
+			tst.Errorf("Concurrent split search test failed, tie resolved in favour of feature4.")
+		}
+	}
+}
+
+func Test_ExpandContext(tst *testing.T) {
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.MaxDepth, settings.ExpandWorkers = 20, 4
+
+	sequential, concurrent := new(DecisionTree), new(DecisionTree)
+	sequential.InitRoot(settings, prepareSyntheticObservations(300, 0.1, 6))
+	sequential.Expand(true)
+	concurrent.InitRoot(settings, prepareSyntheticObservations(300, 0.1, 6))
+	if err := concurrent.ExpandContext(context.Background()); err != nil {
+		tst.Fatalf("Concurrent expansion failed: %s", err.Error())
+	}
+	if sequential.GetSerializedModel() != concurrent.GetSerializedModel() {
+		tst.Errorf("Concurrent expansion test failed, the trees differ.")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	t := new(DecisionTree)
+	t.InitRoot(settings, prepareSyntheticObservations(300, 0.1, 6))
+	if err := t.ExpandContext(cancelled); err != context.Canceled {
+		tst.Errorf("Cancelled expansion test failed. Expected %v, got %v.", context.Canceled, err)
+	}
+	if got, err := t.Classify(&Observation{"feature1": 1.0, "feature2": 1.0}); !t.IsLeaf() || got == nil || err != nil {
+		tst.Errorf("Cancelled expansion test failed, the root should be a leaf (got %v, %v).", got, err)
+	}
+
+	invalid := prepareSyntheticObservations(300, 0.1, 6)
+	(*invalid[17])[TARGET_KEY] = []int{1}
+	t = new(DecisionTree)
+	t.InitRoot(settings, invalid)
+	if err := t.ExpandContext(context.Background()); err == nil {
+		tst.Errorf("Expansion error test failed, no error returned.")
+	}
+}
+
+func Test_HistogramSplits(tst *testing.T) {
+	grow := func(observations []*Observation, bins int) *DecisionTree {
+		settings := getSettings("supergrow", TARGET_KEY)
+		settings.MaxDepth, settings.HistogramBins = 20, bins
+		t := new(DecisionTree)
+		t.InitRoot(settings, observations)
+		t.Expand(true)
+		return t
+	}
+
+	// with a bin for every distinct value, the histogram splits are the exact splits
+	exact, binned := grow(prepareSyntheticObservations(200, 0.1, 7), 0), grow(prepareSyntheticObservations(200, 0.1, 7), 255)
+	if exact.GetSerializedModel() != binned.GetSerializedModel() {
+		tst.Errorf("Histogram split test failed, the trees differ.")
+	}
+
+	t := grow(prepareSyntheticObservations(2000, 0.05, 8), 16)
+	successes := 0
+	for _, obs := range prepareSyntheticObservations(500, 0.0, 9) {
+		if got, _ := t.Classify(obs); got == (*obs)[TARGET_KEY] {
+			successes++
+		}
+	}
+	if successes < 450 {
+		tst.Errorf("Histogram tree success rate is %d/500.", successes)
+	} else {
+		tst.Logf("Histogram tree success rate is %d/500.", successes)
+	}
+
+	// the histograms of the larger child are derived by subtraction
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.HistogramBins = 16
+	root := new(DecisionTree)
+	root.InitRoot(settings, prepareSyntheticObservations(500, 0.1, 10))
+	predictor, index, _, _ := root.FindBestSplit()
+	root.splitNode(predictor, *index)
+	for _, child := range []*DecisionTree{root.left, root.right} {
+		fresh := *child
+		fresh.histograms = nil
+		for _, p := range []string{"feature1", "feature2", "feature3"} {
+			derived, _ := child.histogramOf(p)
+			built, _ := fresh.histogramOf(p)
+			for b := range built.bins {
+				if derived.sizes[b] != built.sizes[b] || math.Abs(derived.bins[b].impurity()-built.bins[b].impurity()) > 1e-9 {
+					tst.Errorf("Histogram subtraction test failed for %s, bin %d.", p, b)
+				}
+			}
+		}
+	}
+
+	// absent predictors get a single bin, predictors with text values are scanned exactly
+	observations := prepareSyntheticObservations(200, 0.1, 11)
+	for _, obs := range observations {
+		(*obs)["feature5"] = "low"
+		if (*obs)["feature1"].(float64) > 5 {
+			(*obs)["feature5"] = "high"
+		}
+	}
+	settings.Predictors = &[]string{"feature1", "feature2", "feature3", "feature4", "feature5"}
+	root = new(DecisionTree)
+	if err := root.InitRoot(settings, observations); err != nil {
+		tst.Fatalf("Histogram test with absent and text predictors failed: %s", err.Error())
+	}
+	if !root.isBinned("feature4") || root.isBinned("feature5") || len(root.grow.binning.thresholds[root.grow.binning.predictors["feature4"]]) != 0 {
+		tst.Errorf("Histogram test with absent and text predictors failed, wrong binning.")
+	}
+	if err := root.Expand(true); err != nil || root.IsLeaf() {
+		tst.Errorf("Histogram test with absent and text predictors failed to grow the tree.")
+	}
+}
+
+func Test_GrowDataset(tst *testing.T) {
+	regression := getRegressionSettings(MSEPurity{}, MeanEstimator{})
+	regression.MaxDepth = 5
+	categorical := getSettings("supergrow", TARGET_KEY)
+	categorical.Predictors, categorical.CategoricalPredictors = &[]string{"country", "feature1"}, &[]string{"country"}
+	weighted := getSettings("supergrow", TARGET_KEY)
+	weighted.MaxDepth, weighted.BalancedClassWeights, weighted.MinLeafSize = 20, true, 3
+	cases := []struct {
+		name         string
+		settings     *Options
+		observations func() []*Observation
+	}{
+		{"synthetic", getSettings("supergrow", TARGET_KEY), func() []*Observation { return prepareSyntheticObservations(300, 0.1, 11) }},
+		{"balanced", weighted, prepareImbalancedObservations},
+		{"categorical", categorical, prepareCategoricalObservations},
+		{"missing values", getSettings("supergrow", TARGET_KEY), prepareMissingValueObservations},
+		{"regression", regression, prepareRegressionObservations},
+	}
+
+	for _, c := range cases {
+		expected := new(DecisionTree)
+		expected.InitRoot(c.settings, c.observations())
+		expected.Expand(true)
+
+		d, err := NewDataset(c.observations(), c.settings)
+		if err != nil {
+			tst.Fatalf("Dataset test (%s) failed: %s", c.name, err.Error())
+		}
+		t := new(DecisionTree)
+		if err := t.GrowDataset(c.settings, d); err != nil {
+			tst.Fatalf("Columnar growth test (%s) failed: %s", c.name, err.Error())
+		}
+		if expected.GetSerializedModel() != t.GetSerializedModel() {
+			tst.Errorf("Columnar growth test (%s) failed, the trees differ:\n%s\n%s", c.name, expected.GetSerializedModel(), t.GetSerializedModel())
+		}
+	}
+
+	observations := prepareMissingValueObservations()
+	d, _ := NewDataset(observations, getSettings("supergrow", TARGET_KEY))
+	for i, obs := range d.Observations() {
+		if len(*obs) != len(*observations[i]) || (*obs)["feature1"] != (*observations[i])["feature1"] {
+			tst.Errorf("Dataset round-trip test failed, got %v instead of %v.", *obs, *observations[i])
+		}
+	}
+
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.SplitStrategy = MAEPurity{}
+	if err := new(DecisionTree).GrowDataset(settings, d); err == nil {
+		tst.Errorf("Columnar growth test failed, MAE split strategy accepted.")
+	}
+	invalid := prepareSyntheticObservations(10, 0.0, 12)
+	(*invalid[3])["feature2"] = "text"
+	if _, err := NewDataset(invalid, settings); err == nil {
+		tst.Errorf("Dataset test failed, mixed column types accepted.")
+	}
+	d.Columns[0].Floats = d.Columns[0].Floats[1:]
+	if err := new(DecisionTree).GrowDataset(getSettings("supergrow", TARGET_KEY), d); err == nil {
+		tst.Errorf("Columnar growth test failed, a short column accepted.")
+	}
+}
+
+func BenchmarkExpandDataset(b *testing.B) {
+	settings := getSettings("supergrow", "__target")
+	d, _ := NewDataset(prepareTestObservations([]string{}), settings)
+	for n := 0; n < b.N; n++ {
+		new(DecisionTree).GrowDataset(settings, d)
+	}
+}
+
+func BenchmarkExpandNodeSynthetic(b *testing.B) {
+	observations := prepareSyntheticObservations(5000, 0.1, 13)
+	for n := 0; n < b.N; n++ {
+		t := new(DecisionTree)
+		t.InitRoot(getSettings("supergrow", TARGET_KEY), observations)
+		t.Expand(true)
+	}
+}
+
+func BenchmarkExpandDatasetSynthetic(b *testing.B) {
+	settings := getSettings("supergrow", TARGET_KEY)
+	d, _ := NewDataset(prepareSyntheticObservations(5000, 0.1, 13), settings)
+	for n := 0; n < b.N; n++ {
+		new(DecisionTree).GrowDataset(settings, d)
+	}
+}
@@ -0,0 +1,133 @@
+package decision_tree
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Fill colours of the classes in DOT graphs, assigned to the classes in ascending order.
+var dotClassColors = []string{"#8dd3c7", "#ffffb3", "#bebada", "#fb8072", "#80b1d3", "#fdb462", "#b3de69", "#fccde5", "#d9d9d9", "#bc80bd"}
+
+// Options of writing trees as Graphviz graphs (see WriteDOT).
+type DOTOptions struct {
+	MaxDepth     int  // Depth of the deepest nodes written, deeper subtrees being elided (0 writes the whole tree)
+	ColorByClass bool // Fill every node with the colour of its majority class (classification trees only)
+}
+
+// Writes the tree as a Graphviz DOT graph, to be rendered e.g. by `dot -Tpng`. Internal nodes show their split rule,
+// leaves their classification and, in classification trees, the probability of the predicted class (as returned
+// by ClassifyProba) and the counts of the classes. All nodes show the number (the total weight) of their training
+// observations when known. The edges are labelled ifLeq (to the left subtrees) and ifGt (to the right ones), as in
+// the serialized model. Nil options write the whole tree without colours.
+func (t *DecisionTree) WriteDOT(w io.Writer, opts *DOTOptions) error {
+	if opts == nil {
+		opts = &DOTOptions{}
+	}
+	colors := map[Value]string{}
+	if opts.ColorByClass && t.ClassCounts != nil {
+		for i, class := range sortedClasses(t.ClassCounts) {
+			colors[class] = dotClassColors[i%len(dotClassColors)]
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	writer.WriteString("digraph tree {\n\tnode [shape=box, style=\"rounded,filled\", fillcolor=white, fontname=helvetica];\n\tedge [fontname=helvetica];\n")
+	nodes := 0
+	t.writeDOTNode(writer, opts, t, colors, 0, &nodes)
+	writer.WriteString("}\n")
+	return writer.Flush()
+}
+
+// Writes this node of the tree rooted in root and its subtree, numbering the nodes in preorder.
+// Output: the number of the node.
+func (t *DecisionTree) writeDOTNode(w *bufio.Writer, opts *DOTOptions, root *DecisionTree, colors map[Value]string, depth int, nodes *int) int {
+	id := *nodes
+	*nodes++
+
+	lines := []string{}
+	if !t.IsLeaf() {
+		lines = append(lines, t.ruleString())
+	} else if t.ClassCounts != nil {
+		classification, _ := _str(t.Classification)
+		lines = append(lines, "class = "+classification)
+		if root.ClassCounts != nil {
+			lines = append(lines, "p = "+strconv.FormatFloat(root.classProbabilities(t)[t.Classification], 'f', 3, 64))
+		}
+		lines = append(lines, "counts = "+t.dotCounts(root))
+	} else {
+		value, _ := _str(t.Classification)
+		lines = append(lines, "value = "+value)
+	}
+	if samples := t.dotSamples(); samples > 0.0 {
+		lines = append(lines, "samples = "+strconv.FormatFloat(samples, 'g', 6, 64))
+	}
+
+	attributes := "label=" + dotQuote(lines)
+	if color, ok := colors[t.dotMajority()]; ok {
+		attributes += ", fillcolor=\"" + color + "\""
+	}
+	w.WriteString("\t" + strconv.Itoa(id) + " [" + attributes + "];\n")
+
+	if t.IsLeaf() {
+		return id
+	} else if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+		elided := *nodes
+		*nodes++
+		w.WriteString("\t" + strconv.Itoa(elided) + " [label=\"...\", shape=plaintext, style=\"\"];\n")
+		w.WriteString("\t" + strconv.Itoa(id) + " -> " + strconv.Itoa(elided) + ";\n")
+		return id
+	}
+
+	for _, child := range []struct {
+		node  *DecisionTree
+		label string
+	}{{t.left, "ifLeq"}, {t.right, "ifGt"}} {
+		childId := child.node.writeDOTNode(w, opts, root, colors, depth+1, nodes)
+		w.WriteString("\t" + strconv.Itoa(id) + " -> " + strconv.Itoa(childId) + " [label=\"" + child.label + "\"];\n")
+	}
+	return id
+}
+
+// Returns the number (total weight) of the training observations of this node, or 0 if unknown.
+func (t *DecisionTree) dotSamples() float64 {
+	if t.ClassCounts == nil {
+		return float64(len(t.Observations))
+	}
+	total := 0.0
+	for _, count := range t.ClassCounts {
+		total += count
+	}
+	return total
+}
+
+// Returns the counts of all classes of the tree rooted in root among the training observations of this node,
+// e.g. [0: 3, 1: 1].
+func (t *DecisionTree) dotCounts(root *DecisionTree) string {
+	counts := []string{}
+	for _, class := range sortedClasses(root.ClassCounts) {
+		name, _ := _str(class)
+		counts = append(counts, name+": "+strconv.FormatFloat(t.ClassCounts[class], 'g', 6, 64))
+	}
+	return "[" + strings.Join(counts, ", ") + "]"
+}
+
+// Returns the most frequent class of this node (the first one in ascending order on ties), or nil for regression trees.
+func (t *DecisionTree) dotMajority() (majority Value) {
+	for _, class := range sortedClasses(t.ClassCounts) {
+		if majority == nil || t.ClassCounts[class] > t.ClassCounts[majority] {
+			majority = class
+		}
+	}
+	return
+}
+
+// Returns the lines as a quoted DOT string.
+func dotQuote(lines []string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for i, line := range lines {
+		lines[i] = escaper.Replace(line)
+	}
+	return "\"" + strings.Join(lines, `\n`) + "\""
+}
@@ -0,0 +1,53 @@
+package decision_tree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_WriteDOT(tst *testing.T) {
+	t := new(DecisionTree)
+	settings := getSettings("supergrow", TARGET_KEY)
+	settings.Predictors, settings.CategoricalPredictors = &[]string{"country", "feature1"}, &[]string{"country"}
+	t.InitRoot(settings, prepareCategoricalObservations())
+	t.Expand(true)
+
+	var buffer bytes.Buffer
+	if err := t.WriteDOT(&buffer, &DOTOptions{ColorByClass: true}); err != nil {
+		tst.Fatalf("Writing the DOT graph failed: %s", err.Error())
+	}
+	graph := buffer.String()
+	expected := []string{"digraph tree {", `0 [label="country in {AT,CZ}\nsamples = 10"`,
+		`label="class = 1.000000\np = 1.000\ncounts = [0.000000: 0, 1.000000: 2]\nsamples = 2"`,
+		`0 -> 1 [label="ifLeq"]`, `0 -> 4 [label="ifGt"]`, `fillcolor="#8dd3c7"`, `fillcolor="#ffffb3"`}
+	for _, e := range expected {
+		if !strings.Contains(graph, e) {
+			tst.Errorf("DOT export test failed, %s not found in:\n%s", e, graph)
+		}
+	}
+
+	// the probabilities are those of ClassifyProba
+	settings.LaplaceSmoothing = 1.0
+	buffer.Reset()
+	t.WriteDOT(&buffer, nil)
+	if graph := buffer.String(); !strings.Contains(graph, `label="class = 1.000000\np = 0.750\ncounts = [0.000000: 0, 1.000000: 2]`) {
+		tst.Errorf("DOT export smoothing test failed, got:\n%s", graph)
+	}
+
+	regression := new(DecisionTree)
+	regressionSettings := getRegressionSettings(MSEPurity{}, MeanEstimator{})
+	regressionSettings.MaxDepth = 5
+	regression.InitRoot(regressionSettings, prepareRegressionObservations())
+	regression.Expand(true)
+	buffer.Reset()
+	regression.WriteDOT(&buffer, &DOTOptions{ColorByClass: true})
+	if graph := buffer.String(); !strings.Contains(graph, `label="value = 60.000000\nsamples = 2"`) || strings.Contains(graph, "fillcolor=\"#") {
+		tst.Errorf("DOT export regression test failed, got:\n%s", graph)
+	}
+	buffer.Reset()
+	regression.WriteDOT(&buffer, &DOTOptions{MaxDepth: 1})
+	if graph := buffer.String(); strings.Count(graph, `[label="...", shape=plaintext`) != 2 || strings.Contains(graph, "value = ") {
+		tst.Errorf("DOT export depth limit test failed, got:\n%s", graph)
+	}
+}
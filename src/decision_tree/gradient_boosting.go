@@ -0,0 +1,226 @@
+package decision_tree
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+const GB_RESIDUAL_KEY = "__gbResidual"
+const GB_HESSIAN_KEY = "__gbHessian"
+
+// Gradient boosted trees for binary classification: a sequence of shallow regression trees is fitted to the gradients
+// of the log-loss, each tree adding a (shrunk) Newton step to the log-odds of the positive class.
+type GradientBoostingClassifier struct {
+	Trees               []*DecisionTree
+	NumRounds           int      // Maximal number of boosting rounds (trees)
+	LearningRate        float64  // Shrinkage applied to every tree; 0 means 0.1
+	Subsample           float64  // Fraction of observations sampled (without replacement) for every tree; 0 means all observations
+	EarlyStoppingRounds int      // Stop after this many rounds without improvement of the validation loss; 0 disables early stopping
+	Seed                int64    // Seed of the random generator driving the subsampling
+	Options             *Options // Per-tree grow options (MaxDepth, MinSplitSize, ...); TargetAttribute denotes the binary target
+
+	PositiveClass Value   // The class whose probability is modelled; if nil, the greater of the two classes is used
+	NegativeClass Value   // The other class (determined from the observations)
+	InitialScore  float64 // Log-odds of the positive class in the (weighted) training observations, the starting point of boosting
+}
+
+// Newton step of the log-loss in a leaf: the (weighted) sum of gradients over the (weighted) sum of hessians of its observations.
+type newtonStepEstimator struct{}
+
+func (n newtonStepEstimator) Estimate(data []*Observation, targetAttribute string) (estimate Value, err error) {
+	return n.estimateWeighted(data, targetAttribute, unitWeight)
+}
+
+func (n newtonStepEstimator) estimateWeighted(data []*Observation, targetAttribute string, weightOf func(*Observation) float64) (estimate Value, err error) {
+	sumResiduals, sumHessians := 0.0, 0.0
+	for _, obs := range data {
+		sumResiduals += weightOf(obs) * (*obs)[GB_RESIDUAL_KEY].(float64)
+		sumHessians += weightOf(obs) * (*obs)[GB_HESSIAN_KEY].(float64)
+	}
+	if sumHessians < 1e-12 {
+		return 0.0, nil
+	}
+	return sumResiduals / sumHessians, nil
+}
+
+// Fits the boosted trees to the observations. If validation observations are given, the log-loss on them is tracked after
+// every round and (with EarlyStoppingRounds set) boosting stops once it stops improving, keeping the best number of trees.
+func (g *GradientBoostingClassifier) Fit(observations []*Observation, validation []*Observation) error {
+	if g.NumRounds <= 0 {
+		return errors.New("Number of boosting rounds must be positive.")
+	}
+	if err := g.findClasses(observations); err != nil {
+		return err
+	}
+	if g.LearningRate <= 0.0 {
+		g.LearningRate = 0.1
+	}
+	if err := checkWeights(observations, g.Options); err != nil {
+		return err
+	}
+
+	treeOptions := *g.Options
+	treeOptions.TargetAttribute, treeOptions.SplitStrategy, treeOptions.LeafEstimator = GB_RESIDUAL_KEY, MSEPurity{}, newtonStepEstimator{}
+	treeOptions.ClassWeights, treeOptions.BalancedClassWeights, treeOptions.LossMatrix = nil, false, nil
+	weigher := &DecisionTree{Options: &treeOptions} // the sample weights of the observations (see Options.WeightAttribute)
+
+	// working copies of the observations, holding the gradients (residuals) and hessians of the current round
+	working, labels := make([]*Observation, len(observations)), make([]float64, len(observations))
+	positives, total := 0.0, 0.0
+	for i, obs := range observations {
+		copied := Observation{}
+		for attr, val := range *obs {
+			copied[attr] = val
+		}
+		working[i] = &copied
+		labels[i] = g.label((*obs)[g.Options.TargetAttribute])
+		positives, total = positives+labels[i]*weigher.sampleWeight(obs), total+weigher.sampleWeight(obs)
+	}
+	if total <= 0.0 {
+		return errors.New("Cannot fit on observations without weight.")
+	}
+	p0 := math.Min(math.Max(positives/total, 1e-6), 1-1e-6)
+	g.InitialScore = math.Log(p0 / (1 - p0))
+
+	scores := make([]float64, len(observations))
+	for i := range scores {
+		scores[i] = g.InitialScore
+	}
+	validationScores := make([]float64, len(validation))
+	for i := range validationScores {
+		validationScores[i] = g.InitialScore
+	}
+
+	random := rand.New(rand.NewSource(g.Seed))
+	bestLoss, bestRounds := math.Inf(1), 0
+	g.Trees = []*DecisionTree{}
+	for round := 0; round < g.NumRounds; round++ {
+		for i, obs := range working {
+			p := sigmoid(scores[i])
+			(*obs)[GB_RESIDUAL_KEY], (*obs)[GB_HESSIAN_KEY] = labels[i]-p, p*(1-p)
+		}
+
+		tree := new(DecisionTree)
+		if err := tree.InitRoot(&treeOptions, g.subsample(working, random)); err != nil {
+			return err
+		}
+		if err := tree.Expand(true); err != nil {
+			return err
+		}
+		g.Trees = append(g.Trees, tree)
+
+		for i, obs := range working {
+			step, err := tree.Predict(obs)
+			if err != nil {
+				return err
+			}
+			scores[i] += g.LearningRate * step
+		}
+
+		if len(validation) == 0 {
+			continue
+		}
+		loss := 0.0
+		for i, obs := range validation {
+			step, err := tree.Predict(obs)
+			if err != nil {
+				return err
+			}
+			validationScores[i] += g.LearningRate * step
+			loss += logLoss(g.label((*obs)[g.Options.TargetAttribute]), sigmoid(validationScores[i]))
+		}
+		if loss < bestLoss {
+			bestLoss, bestRounds = loss, round+1
+		} else if g.EarlyStoppingRounds > 0 && round+1-bestRounds >= g.EarlyStoppingRounds {
+			break
+		}
+	}
+
+	if len(validation) > 0 && g.EarlyStoppingRounds > 0 {
+		g.Trees = g.Trees[:bestRounds]
+	}
+	return nil
+}
+
+// Returns the probability of the positive class for a new observation o.
+func (g *GradientBoostingClassifier) PredictProba(o *Observation) (float64, error) {
+	score := g.InitialScore
+	for _, tree := range g.Trees {
+		step, err := tree.Predict(o)
+		if err != nil {
+			return 0.0, err
+		}
+		score += g.LearningRate * step
+	}
+	return sigmoid(score), nil
+}
+
+// Returns the classification for a new observation o: the positive class iff its probability is at least 0.5.
+func (g *GradientBoostingClassifier) Classify(o *Observation) (Value, error) {
+	p, err := g.PredictProba(o)
+	if err != nil {
+		return nil, err
+	}
+	if p >= 0.5 {
+		return g.PositiveClass, nil
+	}
+	return g.NegativeClass, nil
+}
+
+// Determines the positive and negative class from the (binary) target values of the observations.
+func (g *GradientBoostingClassifier) findClasses(observations []*Observation) error {
+	counts := newClassCounts(gini)
+	for _, obs := range observations {
+		if err := counts.add((*obs)[g.Options.TargetAttribute], 1.0); err != nil {
+			return err
+		}
+	}
+	classes := sortedClasses(counts.counts)
+	if len(classes) != 2 {
+		return errors.New("Gradient boosting requires a binary target.")
+	}
+
+	if g.PositiveClass == nil {
+		g.PositiveClass = classes[1]
+	}
+	if isEq, _ := _eq(g.PositiveClass, classes[0]); isEq {
+		g.NegativeClass = classes[1]
+	} else if isEq, _ := _eq(g.PositiveClass, classes[1]); isEq {
+		g.NegativeClass = classes[0]
+	} else {
+		return errors.New("The positive class does not occur in the observations.")
+	}
+	return nil
+}
+
+// Returns 1 for the positive class and 0 otherwise.
+func (g *GradientBoostingClassifier) label(target Value) float64 {
+	if isEq, _ := _eq(g.PositiveClass, target); isEq {
+		return 1.0
+	}
+	return 0.0
+}
+
+// Returns the observations used to fit the next tree: a random fraction (Subsample) of them, or all of them.
+func (g *GradientBoostingClassifier) subsample(observations []*Observation, random *rand.Rand) []*Observation {
+	if g.Subsample <= 0.0 || g.Subsample >= 1.0 {
+		return append([]*Observation{}, observations...)
+	}
+
+	k := int(math.Max(1, math.Round(g.Subsample*float64(len(observations)))))
+	sample := make([]*Observation, k)
+	for i, index := range random.Perm(len(observations))[:k] {
+		sample[i] = observations[index]
+	}
+	return sample
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+func logLoss(label float64, p float64) float64 {
+	p = math.Min(math.Max(p, 1e-15), 1-1e-15)
+	return -label*math.Log(p) - (1-label)*math.Log(1-p)
+}
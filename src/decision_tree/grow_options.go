@@ -7,4 +7,30 @@ type Options struct {
 	SplitStrategy    AbstractPurityMetric
 	TargetAttribute  string
 	Predictors       *[]string
+
+	MinLeafSize         int     // Minimal size (total weight of observations) of each child of a split
+	MinImpurityDecrease float64 // Minimal weighted impurity decrease N_t/N * (impurity - split purity) of a split
+	MaxLeafNodes        int     // Maximal number of leaves of the tree; 0 means unlimited
+
+	LeafEstimator   AbstractLeafEstimator // Calculates the values predicted by leaves; nil means majority vote (classification)
+	WeightAttribute string                // Attribute holding the (numeric, non-negative) sample weights of observations; empty means unit weights
+
+	ClassWeights         map[Value]float64 // Weights multiplying the weights of observations of the given target classes in impurities and leaf values (unlisted classes weigh 1)
+	BalancedClassWeights bool              // Weighs the classes inversely to their frequency among the root observations (overrides ClassWeights)
+	LossMatrix           LossMatrix        // Misclassification costs minimized by the gini splits and the leaf assignment; nil means 0/1 loss
+
+	CategoricalPredictors *[]string // Predictors (a subset of Predictors) split by category subsets instead of thresholds
+	MaxSurrogates         int       // Number of surrogate splits learned per node for observations missing the split predictor
+
+	LaplaceSmoothing float64 // Additive (Laplace) smoothing of the class probabilities returned by ClassifyProba
+
+	MaxFeatures int   // Number of predictors randomly chosen as split candidates in every node; 0 means all predictors
+	Seed        int64 // Seed of the random choices made while growing the tree (e.g. the choice of split candidates)
+
+	SplitWorkers  int // Number of goroutines evaluating the split predictors of a node concurrently; 0 or 1 means sequential search
+	ExpandWorkers int // Maximal number of goroutines growing subtrees concurrently in ExpandContext; 0 means runtime.NumCPU()
+
+	HistogramBins int // Finds splits of numeric predictors from histograms of at most this many (up to 255) quantile bins; 0 means exact splits
+
+	sampleWeights map[*Observation]float64 // Weights of the observations set internally (e.g. by AdaBoost), overriding WeightAttribute
 }
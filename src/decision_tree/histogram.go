@@ -0,0 +1,228 @@
+package decision_tree
+
+import (
+	"errors"
+	"sort"
+)
+
+const MAX_HISTOGRAM_BINS = 255
+
+// Quantile bins of the numeric predictors of a tree, computed once from the root observations (see Options.HistogramBins).
+type binning struct {
+	predictors map[string]int       // Position of every binned predictor
+	thresholds [][]float64          // thresholds[p][b-1] is the smallest value of the bin b of the predictor p (bin 0 has no lower bound)
+	rows       map[*Observation]int // Row of every root observation
+	codes      [][]uint8            // codes[p][row] is the bin of the value of the predictor p in the row (if present)
+}
+
+// Per-bin statistics of the target values in a node for one binned predictor.
+type histogram struct {
+	bins    []targetAccumulator
+	sizes   []int     // Number of observations in every bin
+	weights []float64 // Total sample weight of the observations in every bin (see isLargeEnough)
+}
+
+// Bins the numeric predictors into at most Options.HistogramBins quantile bins of the given observations. Predictors
+// with values not parsing as numbers are left out, their splits are found by the exact scan.
+func (t *DecisionTree) newBinning(observations []*Observation) (*binning, error) {
+	bins := t.Options.HistogramBins
+	if bins > MAX_HISTOGRAM_BINS {
+		return nil, errors.New("Too many histogram bins.")
+	}
+
+	b := &binning{map[string]int{}, [][]float64{}, make(map[*Observation]int, len(observations)), [][]uint8{}}
+predictors:
+	for _, predictor := range *t.Options.Predictors {
+		if t.isCategorical(predictor) {
+			continue
+		}
+		values := []float64{}
+		for _, obs := range observations {
+			if val := (*obs)[predictor]; !isMissing(val) {
+				v, err := _float(val)
+				if err != nil {
+					continue predictors
+				}
+				values = append(values, v)
+			}
+		}
+		b.predictors[predictor] = len(b.thresholds)
+		b.thresholds = append(b.thresholds, quantileThresholds(values, bins))
+		b.codes = append(b.codes, make([]uint8, len(observations)))
+	}
+
+	for row, obs := range observations {
+		b.rows[obs] = row
+		for predictor, p := range b.predictors {
+			if val := (*obs)[predictor]; !isMissing(val) {
+				v, _ := _float(val)
+				b.codes[p][row] = uint8(sort.Search(len(b.thresholds[p]), func(i int) bool { return b.thresholds[p][i] > v }))
+			}
+		}
+	}
+	return b, nil
+}
+
+// Returns the lower bounds of (at most) the given number of bins holding roughly equal numbers of the values.
+// Bins never split a run of equal values, so there are fewer bins when the values repeat (and a single one without values).
+func quantileThresholds(values []float64, bins int) (thresholds []float64) {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+	for k := 1; k < bins; k++ {
+		threshold := values[k*len(values)/bins]
+		if threshold > values[0] && (len(thresholds) == 0 || threshold > thresholds[len(thresholds)-1]) {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	return
+}
+
+// Returns true iff the splits of this node on the predictor are found from its histogram: in histogram mode, for numeric
+// predictors and split strategies evaluated by accumulators.
+func (t *DecisionTree) isBinned(predictor string) bool {
+	if t.grow == nil || t.grow.binning == nil {
+		return false
+	}
+	if _, ok := t.Options.SplitStrategy.(scanningPurityMetric); !ok {
+		return false
+	}
+	_, ok := t.grow.binning.predictors[predictor]
+	return ok
+}
+
+// Returns the histogram of the node for the binned predictor, building it from the observations if needed.
+func (t *DecisionTree) histogramOf(predictor string) (*histogram, error) {
+	p := t.grow.binning.predictors[predictor]
+	if t.histograms == nil {
+		t.histograms = make([]*histogram, len(t.grow.binning.thresholds))
+	} else if t.histograms[p] != nil {
+		return t.histograms[p], nil
+	}
+
+	metric := t.Options.SplitStrategy.(scanningPurityMetric)
+	bins := len(t.grow.binning.thresholds[p]) + 1
+	h := &histogram{make([]targetAccumulator, bins), make([]int, bins), make([]float64, bins)}
+	for b := range h.bins {
+		h.bins[b] = t.newAccumulator(metric)
+	}
+	for _, obs := range t.Observations {
+		if !isMissing((*obs)[predictor]) {
+			b := t.grow.binning.codes[p][t.grow.binning.rows[obs]]
+			if err := h.bins[b].add((*obs)[t.Options.TargetAttribute], t.weightOf(obs)); err != nil {
+				return nil, err
+			}
+			h.sizes[b]++
+			h.weights[b] += t.sampleWeight(obs)
+		}
+	}
+	t.histograms[p] = h
+	return h, nil
+}
+
+// Finds the best split of the observations in node t on the binned predictor by scanning the bins of its histogram,
+// only considering splits between bins.
+// Output: index i of the best split (left region is Observations[:i], right region is Observations[i:]) and its purity.
+// Side effects: observations in the node are reordered by the bins of the predictor (see sortByBin).
+func (t *DecisionTree) histogramSplit(predictor string) (ptrBestSplitIndex *int, ptrPurityAtSplit *float64, err error) {
+	h, err := t.histogramOf(predictor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metric := t.Options.SplitStrategy.(scanningPurityMetric)
+	left, right := t.newAccumulator(metric), t.newAccumulator(metric)
+	weightLeft, weightRight := 0.0, 0.0
+	for b, bin := range h.bins {
+		right.merge(bin, 1.0)
+		weightRight += h.weights[b]
+	}
+
+	sizeLeft := 0
+	for b := 1; b < len(h.bins); b++ {
+		left.merge(h.bins[b-1], 1.0)
+		right.merge(h.bins[b-1], -1.0)
+		sizeLeft += h.sizes[b-1]
+		weightLeft, weightRight = weightLeft+h.weights[b-1], weightRight-h.weights[b-1]
+		if h.sizes[b-1] == 0 || sizeLeft == 0 || !t.isLargeEnough(weightLeft, weightRight) {
+			continue
+		}
+
+		purity := metric.splitPurity(left, right)
+		if ptrPurityAtSplit == nil || *ptrPurityAtSplit > purity {
+			i := sizeLeft
+			ptrPurityAtSplit, ptrBestSplitIndex = &purity, &i
+		}
+	}
+
+	t.sortByPredictor(predictor)
+	return
+}
+
+// Sorts the observations in this node by the bins of the predictor (counting sort, the order within a bin being arbitrary),
+// the observations missing the predictor coming last.
+func (t *DecisionTree) sortByBin(predictor string) {
+	p := t.grow.binning.predictors[predictor]
+	buckets := make([][]*Observation, len(t.grow.binning.thresholds[p])+2)
+	for _, obs := range t.Observations {
+		b := len(buckets) - 1
+		if !isMissing((*obs)[predictor]) {
+			b = int(t.grow.binning.codes[p][t.grow.binning.rows[obs]])
+		}
+		buckets[b] = append(buckets[b], obs)
+	}
+
+	i := 0
+	for _, bucket := range buckets {
+		i += copy(t.Observations[i:], bucket)
+	}
+}
+
+// Hands the histograms of this (just split) node down to its children: the histograms of the smaller child are built
+// from its observations, those of the larger child are obtained by subtracting them from the histograms of this node.
+func (t *DecisionTree) subtractHistograms() error {
+	smaller, larger := t.left, t.right
+	if len(smaller.Observations) > len(larger.Observations) {
+		smaller, larger = larger, smaller
+	}
+
+	larger.histograms = make([]*histogram, len(t.histograms))
+	for predictor, p := range t.grow.binning.predictors {
+		parent := t.histograms[p]
+		if parent == nil {
+			continue
+		}
+		h, err := smaller.histogramOf(predictor)
+		if err != nil {
+			return err
+		}
+		for b := range parent.bins {
+			parent.bins[b].merge(h.bins[b], -1.0)
+			parent.sizes[b] -= h.sizes[b]
+			parent.weights[b] -= h.weights[b]
+		}
+		larger.histograms[p] = parent
+	}
+	t.histograms = nil
+	return nil
+}
+
+// Drops the histograms of this node once it has become a leaf (the histograms of split nodes are handed down to their children).
+func (t *DecisionTree) releaseHistograms() {
+	if t.IsLeaf() {
+		t.histograms = nil
+	}
+}
+
+// Returns the smallest value of the predictor among the observations.
+func minimumValue(observations []*Observation, predictor string) (minimum Value) {
+	for _, obs := range observations {
+		if val := (*obs)[predictor]; minimum == nil {
+			minimum = val
+		} else if isLess, _ := _lt(val, minimum); isLess {
+			minimum = val
+		}
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package decision_tree
+
+import (
+	"errors"
+	"sort"
+)
+
+// Blueprint interface for calculating the value predicted by a leaf node. When Options.LeafEstimator is nil,
+// leaves predict the majority class of their observations (classification trees).
+type AbstractLeafEstimator interface {
+	Estimate(data []*Observation, targetAttribute string) (estimate Value, err error)
+}
+
+// Implemented by leaf estimators honouring observation weights (see Options.WeightAttribute).
+type weightedLeafEstimator interface {
+	estimateWeighted(data []*Observation, targetAttribute string, weightOf func(*Observation) float64) (estimate Value, err error)
+}
+
+// --------------------------------------------------------------------------------------------------
+
+// Leaf estimator predicting the mean of the (numeric) target values; pairs naturally with the MSEPurity split strategy.
+type MeanEstimator struct{}
+
+func (m MeanEstimator) Estimate(data []*Observation, targetAttribute string) (estimate Value, err error) {
+	return m.estimateWeighted(data, targetAttribute, unitWeight)
+}
+
+func (m MeanEstimator) estimateWeighted(data []*Observation, targetAttribute string, weightOf func(*Observation) float64) (estimate Value, err error) {
+	if len(data) == 0 {
+		return nil, errors.New("Cannot estimate on an empty node!")
+	}
+
+	acc := &moments{}
+	for _, obs := range data {
+		if err := acc.add((*obs)[targetAttribute], weightOf(obs)); err != nil {
+			return nil, err
+		}
+	}
+	if acc.w <= 0.0 {
+		return nil, errors.New("Cannot estimate on a node without weight!")
+	}
+	return acc.sum / acc.w, nil
+}
+
+// --------------------------------------------------------------------------------------------------
+
+// Leaf estimator predicting the median of the (numeric) target values; pairs naturally with the MAEPurity split strategy.
+type MedianEstimator struct{}
+
+func (m MedianEstimator) Estimate(data []*Observation, targetAttribute string) (estimate Value, err error) {
+	return m.estimateWeighted(data, targetAttribute, unitWeight)
+}
+
+// Returns the weighted median: the smallest value at which the cumulative weight reaches half of the total weight,
+// averaged with the next value when the cumulative weight equals exactly half (the usual median for unit weights).
+func (m MedianEstimator) estimateWeighted(data []*Observation, targetAttribute string, weightOf func(*Observation) float64) (estimate Value, err error) {
+	if len(data) == 0 {
+		return nil, errors.New("Cannot estimate on an empty node!")
+	}
+
+	type weightedValue struct{ y, w float64 }
+	values, total := make([]weightedValue, 0, len(data)), 0.0
+	for _, obs := range data {
+		y, err := _float((*obs)[targetAttribute])
+		if err != nil {
+			return nil, err
+		}
+		if w := weightOf(obs); w > 0.0 {
+			values, total = append(values, weightedValue{y, w}), total+w
+		}
+	}
+	if len(values) == 0 {
+		return nil, errors.New("Cannot estimate on a node without weight!")
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].y < values[j].y })
+
+	cumulative := 0.0
+	for i, v := range values {
+		cumulative += v.w
+		if cumulative == total/2 && i+1 < len(values) {
+			return (v.y + values[i+1].y) / 2, nil
+		}
+		if cumulative > total/2 {
+			return v.y, nil
+		}
+	}
+	return values[len(values)-1].y, nil
+}
@@ -0,0 +1,175 @@
+package decision_tree
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options of reading LIBSVM (SVMlight) files.
+type LIBSVMOptions struct {
+	TargetAttribute string         // Name of the attribute holding the labels
+	FeatureNames    map[int]string // Names of the predictors by feature index; the other features are named f<index>
+	AbsentAsMissing bool           // Features absent from a line are missing values (zeros by default, as in the sparse format)
+}
+
+// Reads the LIBSVM file at the given path (see ReadLIBSVM).
+func ReadLIBSVMFile(path string, options *LIBSVMOptions) (*LoadedDataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadLIBSVM(file, options)
+}
+
+// Reads observations from LIBSVM data: every line holds a numeric label and the index:value pairs of the features
+// ("label index:value index:value ..."), followed by an optional # comment. Query ids (qid:...) are ignored, as are empty
+// lines. The predictors are all features named by the options or present in the data, ordered by their index.
+func ReadLIBSVM(r io.Reader, options *LIBSVMOptions) (*LoadedDataset, error) {
+	d := &LoadedDataset{TargetAttribute: options.TargetAttribute, Kinds: map[string]AttributeKind{options.TargetAttribute: NumericAttribute}}
+	indices := map[int]string{}
+	for index, name := range options.FeatureNames {
+		indices[index] = name
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	for line := 1; scanner.Scan(); line++ {
+		obs, err := parseLIBSVMLine(scanner.Text(), options.TargetAttribute, indices)
+		if err != nil {
+			return nil, errors.New("Line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+		if obs != nil {
+			d.Observations = append(d.Observations, obs)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sorted := make([]int, 0, len(indices))
+	for index := range indices {
+		sorted = append(sorted, index)
+	}
+	sort.Ints(sorted)
+	for _, index := range sorted {
+		if _, ok := d.Kinds[indices[index]]; ok {
+			return nil, errors.New("Duplicate attribute " + indices[index] + ".")
+		}
+		d.Predictors = append(d.Predictors, indices[index])
+		d.Kinds[indices[index]] = NumericAttribute
+	}
+
+	if !options.AbsentAsMissing {
+		for _, obs := range d.Observations {
+			for _, predictor := range d.Predictors {
+				if _, ok := (*obs)[predictor]; !ok {
+					(*obs)[predictor] = 0.0
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+// Parses one line of LIBSVM data into an observation (nil for empty lines), naming the new features found in indices.
+func parseLIBSVMLine(line string, target string, indices map[int]string) (*Observation, error) {
+	if comment := strings.IndexByte(line, '#'); comment >= 0 {
+		line = line[:comment]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	label, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, errors.New("invalid label \"" + fields[0] + "\".")
+	}
+	obs := Observation{target: label}
+	seen := map[int]bool{}
+	for _, field := range fields[1:] {
+		if strings.HasPrefix(field, "qid:") {
+			continue
+		}
+		separator := strings.IndexByte(field, ':')
+		if separator < 0 {
+			return nil, errors.New("invalid feature \"" + field + "\".")
+		}
+		index, err1 := strconv.Atoi(field[:separator])
+		value, err2 := strconv.ParseFloat(field[separator+1:], 64)
+		if err1 != nil || err2 != nil || index < 0 {
+			return nil, errors.New("invalid feature \"" + field + "\".")
+		} else if seen[index] {
+			return nil, errors.New("duplicate feature " + strconv.Itoa(index) + ".")
+		}
+		seen[index] = true
+
+		name, ok := indices[index]
+		if !ok {
+			name = "f" + strconv.Itoa(index)
+			indices[index] = name
+		}
+		obs[name] = value
+	}
+	return &obs, nil
+}
+
+// Writes the observations as LIBSVM data: the target value as the label and the value of every predictor as the feature
+// of its index, given by indices (the inverse of LIBSVMOptions.FeatureNames) or, for predictors named f<index>, by their
+// name. Missing values are left out, all other values are written (including zeros), so the data reads back the same
+// with LIBSVMOptions.AbsentAsMissing set. All values must be numeric and every observation must have a label.
+func WriteLIBSVM(w io.Writer, observations []*Observation, targetAttribute string, predictors []string, indices map[string]int) error {
+	features, used := make([]int, len(predictors)), map[int]bool{}
+	for i, predictor := range predictors {
+		index, ok := indices[predictor]
+		if !ok && strings.HasPrefix(predictor, "f") {
+			var err error
+			index, err = strconv.Atoi(predictor[1:])
+			ok = err == nil && index >= 0
+		}
+		if !ok {
+			return errors.New("No feature index of attribute " + predictor + ".")
+		} else if used[index] {
+			return errors.New("Duplicate feature index " + strconv.Itoa(index) + ".")
+		}
+		features[i], used[index] = index, true
+	}
+	order := make([]int, len(predictors)) // positions of the predictors by ascending feature index
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return features[order[i]] < features[order[j]] })
+
+	writer := bufio.NewWriter(w)
+	for _, obs := range observations {
+		if isMissing((*obs)[targetAttribute]) {
+			return errors.New("Missing value of attribute " + targetAttribute + ".")
+		}
+		label, err := _float((*obs)[targetAttribute])
+		if err != nil {
+			return errors.New("Attribute " + targetAttribute + " holds a non-numeric value.")
+		}
+		line := []string{strconv.FormatFloat(label, 'g', -1, 64)}
+		for _, i := range order {
+			val := (*obs)[predictors[i]]
+			if isMissing(val) {
+				continue
+			}
+			v, err := _float(val)
+			if err != nil {
+				return errors.New("Attribute " + predictors[i] + " holds a non-numeric value.")
+			}
+			line = append(line, strconv.Itoa(features[i])+":"+strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		if _, err := writer.WriteString(strings.Join(line, " ") + "\n"); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
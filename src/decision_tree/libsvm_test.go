@@ -0,0 +1,75 @@
+package decision_tree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_ReadLIBSVM(tst *testing.T) {
+	data := "# generated features\n1 1:0.5 3:2 # first\n-1 qid:3 2:1.25\n\n+1 1:-1 2:0 3:7\n"
+	d, err := ReadLIBSVM(strings.NewReader(data), &LIBSVMOptions{TargetAttribute: TARGET_KEY, FeatureNames: map[int]string{3: "age"}})
+	if err != nil {
+		tst.Fatalf("Reading LIBSVM data failed: %s", err.Error())
+	}
+	second := *d.Observations[1]
+	if len(d.Observations) != 3 || strings.Join(d.Predictors, ",") != "f1,f2,age" || second[TARGET_KEY] != -1.0 ||
+		second["f2"] != 1.25 || second["f1"] != 0.0 || second["age"] != 0.0 {
+		tst.Errorf("LIBSVM reading test failed, got predictors %v and %v.", d.Predictors, second)
+	}
+
+	d, _ = ReadLIBSVM(strings.NewReader(data), &LIBSVMOptions{TargetAttribute: TARGET_KEY, AbsentAsMissing: true})
+	if second := *d.Observations[1]; len(second) != 2 || !isMissing(second["f1"]) || (*d.Observations[2])["f2"] != 0.0 {
+		tst.Errorf("LIBSVM missing features test failed, got %v.", second)
+	}
+
+	cases := map[string]string{
+		"1 1:0.5\nx 2:1\n": "Line 2: invalid label \"x\".",
+		"1 1:0.5\n1 2-1\n": "Line 2: invalid feature \"2-1\".",
+		"1 1:0.5 1:2\n":    "Line 1: duplicate feature 1.",
+		"1 1:a\n":          "Line 1: invalid feature \"1:a\".",
+	}
+	for data, expected := range cases {
+		if _, err := ReadLIBSVM(strings.NewReader(data), &LIBSVMOptions{TargetAttribute: TARGET_KEY}); err == nil || err.Error() != expected {
+			tst.Errorf("LIBSVM error test failed. Expected '%s', got '%v'.", expected, err)
+		}
+	}
+}
+
+func Test_WriteLIBSVM(tst *testing.T) {
+	observations := prepareMissingValueObservations()
+	predictors := []string{"feature1", "feature2", "feature3"}
+	var buffer bytes.Buffer
+	if err := WriteLIBSVM(&buffer, observations, TARGET_KEY, predictors, map[string]int{"feature1": 5, "feature2": 7, "feature3": 2}); err != nil {
+		tst.Fatalf("Writing LIBSVM data failed: %s", err.Error())
+	}
+	if line := strings.SplitN(buffer.String(), "\n", 2)[0]; line != "0 2:0 7:100" {
+		tst.Errorf("LIBSVM writing test failed, got '%s'.", line)
+	}
+
+	names := map[int]string{5: "feature1", 7: "feature2", 2: "feature3"}
+	d, err := ReadLIBSVM(&buffer, &LIBSVMOptions{TargetAttribute: TARGET_KEY, FeatureNames: names, AbsentAsMissing: true})
+	if err != nil || len(d.Observations) != len(observations) {
+		tst.Fatalf("Reading the written LIBSVM data failed (%v).", err)
+	}
+	for i, obs := range d.Observations {
+		for attribute, val := range *observations[i] {
+			if (*obs)[attribute] != val || len(*obs) != len(*observations[i]) {
+				tst.Errorf("LIBSVM round-trip test failed, got %v instead of %v.", *obs, *observations[i])
+			}
+		}
+	}
+
+	if err := WriteLIBSVM(&buffer, prepareCategoricalObservations(), TARGET_KEY, []string{"country"}, map[string]int{"country": 1}); err == nil {
+		tst.Errorf("LIBSVM writing test failed, categorical values accepted.")
+	}
+	unlabelled := []*Observation{{"f3": 1.0}}
+	if err := WriteLIBSVM(&buffer, unlabelled, TARGET_KEY, []string{"f3"}, nil); err == nil || err.Error() != "Missing value of attribute "+TARGET_KEY+"." {
+		tst.Errorf("LIBSVM writing test failed, got '%v' for a missing label.", err)
+	}
+	buffer.Reset()
+	(*unlabelled[0])[TARGET_KEY] = 1.0
+	if err := WriteLIBSVM(&buffer, unlabelled, TARGET_KEY, []string{"f3"}, nil); err != nil || buffer.String() != "1 3:1\n" {
+		tst.Errorf("LIBSVM writing test failed, got '%s' for a default feature index (%v).", buffer.String(), err)
+	}
+}
@@ -0,0 +1,157 @@
+package decision_tree
+
+import "sort"
+
+// A surrogate split mimics the primary split of a node using a different predictor. Surrogates are used (in the order
+// of decreasing agreement) to route observations missing the primary split predictor.
+type SurrogateSplit struct {
+	Predictor  string  // The surrogate predictor
+	SplitValue Value   // Split value (or CategorySet) of the surrogate predictor, with the same semantics as DecisionTree.SplitValue
+	Inverted   bool    // If true, observations following the surrogate rule to the left continue to the right subtree and vice versa
+	Agreement  float64 // Fraction of training observations routed by the surrogate the same way as by the primary split
+}
+
+// Returns true iff the value denotes a missing predictor value (absent from the observation).
+func isMissing(v Value) bool {
+	return v == nil
+}
+
+// Returns the number of observations in the node having a value for the predictor. Observations are expected to be
+// sorted by the predictor, so that the observations with missing values come last.
+func (t *DecisionTree) presentCount(predictor string) int {
+	return sort.Search(len(t.Observations), func(i int) bool {
+		return isMissing((*t.Observations[i])[predictor])
+	})
+}
+
+// Returns true iff the observation continues to the left subtree of this (internal) node. When the split predictor is
+// missing from the observation, the surrogate splits are tried in turn, falling back to the direction taken by
+// the majority of training observations.
+func (t *DecisionTree) routesLeft(o *Observation) (bool, error) {
+	if val := (*o)[*t.SplitPredictor]; !isMissing(val) {
+		return t.goesLeft(val, t.SplitValue)
+	}
+
+	for _, surrogate := range t.Surrogates {
+		if val := (*o)[surrogate.Predictor]; !isMissing(val) {
+			goesLeft, err := t.goesLeft(val, surrogate.SplitValue)
+			return goesLeft != surrogate.Inverted, err
+		}
+	}
+	return t.MissingGoesLeft, nil
+}
+
+// Learns up to MaxSurrogates surrogate splits for the primary split of this node. The primary split sends the first
+// splitIndex observations (sorted by the split predictor) to the left and the following ones, up to present, to the right.
+// Only surrogates agreeing with the primary split more often than the majority direction are kept.
+func (t *DecisionTree) learnSurrogates(splitIndex int, present int) error {
+	t.Surrogates = nil
+	if t.Options.MaxSurrogates <= 0 {
+		return nil
+	}
+
+	primaryLeft := make(map[*Observation]bool, present)
+	for i, obs := range t.Observations[:present] {
+		primaryLeft[obs] = i < splitIndex
+	}
+
+	for _, predictor := range *t.Options.Predictors {
+		if predictor == *t.SplitPredictor {
+			continue
+		}
+
+		candidates := []*Observation{}
+		countLeft := 0
+		for _, obs := range t.Observations[:present] {
+			if !isMissing((*obs)[predictor]) {
+				candidates = append(candidates, obs)
+				if primaryLeft[obs] {
+					countLeft++
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		var surrogate *SurrogateSplit
+		if t.isCategorical(predictor) {
+			surrogate = categoricalSurrogate(predictor, candidates, primaryLeft, 2*countLeft >= len(candidates))
+		} else {
+			surrogate = orderedSurrogate(predictor, candidates, primaryLeft)
+		}
+
+		baseline := float64(countLeft) / float64(len(candidates))
+		if baseline < 0.5 {
+			baseline = 1 - baseline
+		}
+		if surrogate != nil && surrogate.Agreement > baseline {
+			t.Surrogates = append(t.Surrogates, surrogate)
+		}
+	}
+
+	sort.SliceStable(t.Surrogates, func(i, j int) bool { return t.Surrogates[i].Agreement > t.Surrogates[j].Agreement })
+	if len(t.Surrogates) > t.Options.MaxSurrogates {
+		t.Surrogates = t.Surrogates[:t.Options.MaxSurrogates]
+	}
+	return nil
+}
+
+// Finds the threshold on an ordered predictor that agrees best with the primary split (possibly inverted).
+func orderedSurrogate(predictor string, candidates []*Observation, primaryLeft map[*Observation]bool) (best *SurrogateSplit) {
+	sort.Sort(ByPredictorValueFloat{predictor, &candidates})
+
+	totalLeft := 0
+	for _, obs := range candidates {
+		if primaryLeft[obs] {
+			totalLeft++
+		}
+	}
+
+	N, leftOfThreshold := len(candidates), 0
+	for i := 1; i < N; i++ {
+		if primaryLeft[candidates[i-1]] {
+			leftOfThreshold++
+		}
+		if isEq, _ := _eq((*candidates[i-1])[predictor], (*candidates[i])[predictor]); isEq {
+			continue
+		}
+
+		// agreeing observations: primary-left below the threshold and primary-right above it
+		agree := leftOfThreshold + (N - i) - (totalLeft - leftOfThreshold)
+		inverted := agree < N-agree
+		if inverted {
+			agree = N - agree
+		}
+
+		agreement := float64(agree) / float64(N)
+		if best == nil || agreement > best.Agreement {
+			best = &SurrogateSplit{predictor, (*candidates[i])[predictor], inverted, agreement}
+		}
+	}
+	return
+}
+
+// Assigns each category of a categorical predictor to the side where the primary split sends most of its observations.
+func categoricalSurrogate(predictor string, candidates []*Observation, primaryLeft map[*Observation]bool, tiesLeft bool) *SurrogateSplit {
+	lefts, rights := map[Value]int{}, map[Value]int{}
+	for _, obs := range candidates {
+		if primaryLeft[obs] {
+			lefts[(*obs)[predictor]]++
+		} else {
+			rights[(*obs)[predictor]]++
+		}
+	}
+
+	agree := 0
+	categories := CategorySet{}
+	for _, category := range categoriesOf(candidates, predictor) {
+		if lefts[category] > rights[category] || (lefts[category] == rights[category] && tiesLeft) {
+			categories = append(categories, category)
+			agree += lefts[category]
+		} else {
+			agree += rights[category]
+		}
+	}
+	return &SurrogateSplit{predictor, categories, false, float64(agree) / float64(len(candidates))}
+}
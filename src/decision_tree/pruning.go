@@ -0,0 +1,333 @@
+package decision_tree
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+const ALPHA_TOLERANCE = 1e-12
+
+// Describes one subtree of the nested sequence produced by the minimal cost-complexity (weakest-link) pruning.
+type PruningStep struct {
+	Alpha  float64 // The smallest complexity parameter for which this subtree is optimal
+	Leaves int     // Number of leaves of the subtree
+	Risk   float64 // Resubstitution risk of the subtree (misclassification rate, or mean squared error for regression trees)
+}
+
+// Returns the sequence of subtrees of this (grown) tree that are optimal for increasing values of the complexity parameter alpha,
+// starting with the full tree (alpha = 0) and ending with the root alone. A subtree T is optimal for alpha iff it minimizes
+// the cost-complexity measure R(T) + alpha*|leaves(T)|, R being the resubstitution risk relative to the size of the root.
+func (t *DecisionTree) CostComplexityPath() ([]PruningStep, error) {
+	steps, _, err := t.weakestLinkPruning(math.Inf(1))
+	return steps, err
+}
+
+// Prunes the tree to the optimal subtree for the given complexity parameter alpha (see CostComplexityPath). Internal nodes
+// of the pruned branches become leaves predicting the leaf value (e.g. majority vote) of their observations.
+func (t *DecisionTree) Prune(alpha float64) error {
+	_, collapsed, err := t.weakestLinkPruning(alpha)
+	if err != nil {
+		return err
+	}
+	return t.collapseNodes(collapsed)
+}
+
+// Chooses the complexity parameter alpha by k-fold cross-validation on the observations of this (grown) node: for every
+// subtree on the cost-complexity path, trees grown on the training folds are pruned to the geometric mean of the alpha
+// range of the subtree and evaluated on the held-out fold. The alpha with the smallest total error is returned
+// (ties are resolved in favour of the simpler tree); it can be passed directly to Prune.
+func (t *DecisionTree) CrossValidateAlpha(folds int, seed int64) (float64, error) {
+	if folds < 2 || folds > len(t.Observations) {
+		return 0.0, errors.New("Invalid number of cross-validation folds.")
+	}
+
+	path, err := t.CostComplexityPath()
+	if err != nil {
+		return 0.0, err
+	}
+	betas := make([]float64, len(path))
+	for i := range path {
+		if i+1 < len(path) {
+			betas[i] = math.Sqrt(path[i].Alpha * path[i+1].Alpha)
+		} else {
+			betas[i] = path[i].Alpha
+		}
+	}
+
+	shuffled := append([]*Observation{}, t.Observations...)
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	errorsAtBeta := make([]float64, len(betas))
+	for fold := 0; fold < folds; fold++ {
+		train, test := []*Observation{}, []*Observation{}
+		for i, obs := range shuffled {
+			if i%folds == fold {
+				test = append(test, obs)
+			} else {
+				train = append(train, obs)
+			}
+		}
+
+		foldTree := new(DecisionTree)
+		if err := foldTree.InitRoot(t.Options, train); err != nil {
+			return 0.0, err
+		}
+		if err := foldTree.Expand(true); err != nil {
+			return 0.0, err
+		}
+
+		// the subtrees are nested, so the fold tree can be pruned progressively
+		for i, beta := range betas {
+			if err := foldTree.Prune(beta); err != nil {
+				return 0.0, err
+			}
+			loss, err := foldTree.testError(test)
+			if err != nil {
+				return 0.0, err
+			}
+			errorsAtBeta[i] += loss
+		}
+	}
+
+	best := 0
+	for i := range betas {
+		if errorsAtBeta[i] <= errorsAtBeta[best] {
+			best = i
+		}
+	}
+	return betas[best], nil
+}
+
+// Reduced-error pruning: walks the tree bottom-up and replaces a subtree with a leaf (predicting the leaf value of its training
+// observations) whenever doing so does not increase the error on the held-out validation observations reaching the subtree.
+// Note that subtrees reached by no validation observations are replaced as well.
+func (t *DecisionTree) PruneWithValidation(observations []*Observation) error {
+	if err := checkWeights(observations, t.Options); err != nil {
+		return err
+	}
+	_, err := t.reducedErrorPrune(observations)
+	return err
+}
+
+// Prunes the subtree rooted in this node using the validation observations reaching it.
+// Output: the total loss of the pruned subtree on the validation observations.
+func (t *DecisionTree) reducedErrorPrune(observations []*Observation) (float64, error) {
+	if t.IsLeaf() {
+		return t.validationLoss(t.Classification, observations)
+	}
+
+	left, right := []*Observation{}, []*Observation{}
+	for _, obs := range observations {
+		if goesLeft, err := t.routesLeft(obs); err != nil {
+			return 0.0, err
+		} else if goesLeft {
+			left = append(left, obs)
+		} else {
+			right = append(right, obs)
+		}
+	}
+
+	lossL, err := t.left.reducedErrorPrune(left)
+	if err != nil {
+		return 0.0, err
+	}
+	lossR, err := t.right.reducedErrorPrune(right)
+	if err != nil {
+		return 0.0, err
+	}
+
+	leafValue, err := t.getLeafValue()
+	if err != nil {
+		return 0.0, err
+	}
+	leafLoss, err := t.validationLoss(leafValue, observations)
+	if err != nil {
+		return 0.0, err
+	}
+
+	if leafLoss <= lossL+lossR {
+		return leafLoss, t.collapse()
+	}
+	return lossL + lossR, nil
+}
+
+// Returns the total (weighted) loss of predicting the given value for all of the observations.
+func (t *DecisionTree) validationLoss(prediction Value, observations []*Observation) (total float64, err error) {
+	for _, obs := range observations {
+		loss, err := t.loss(prediction, (*obs)[t.Options.TargetAttribute])
+		if err != nil {
+			return 0.0, err
+		}
+		total += t.weightOf(obs) * loss
+	}
+	return
+}
+
+// Runs the weakest-link pruning, collapsing the internal nodes with the smallest per-leaf increase of risk for as long as
+// that increase does not exceed maxAlpha. The tree itself is left intact.
+// Output: the visited pruning steps and the set of collapsed nodes.
+func (t *DecisionTree) weakestLinkPruning(maxAlpha float64) ([]PruningStep, map[*DecisionTree]bool, error) {
+	if len(t.Observations) == 0 {
+		return nil, nil, errors.New("Cannot prune a tree without observations.")
+	}
+
+	leafRisks := map[*DecisionTree]float64{}
+	if err := t.collectLeafRisks(leafRisks, t.totalWeight(t.Observations)); err != nil {
+		return nil, nil, err
+	}
+
+	collapsed := map[*DecisionTree]bool{}
+	alpha, steps := 0.0, []PruningStep{}
+	for {
+		risk, leaves, weakest, g := t.weakestLinks(leafRisks, collapsed)
+		steps = append(steps, PruningStep{alpha, leaves, risk})
+		if leaves == 1 || g > maxAlpha+ALPHA_TOLERANCE {
+			break
+		}
+		for _, node := range weakest {
+			collapsed[node] = true
+		}
+		alpha = math.Max(alpha, g)
+	}
+	return steps, collapsed, nil
+}
+
+// Calculates the risk of every node of the tree if it were a leaf, relative to the size (total weight) of the root (N).
+func (t *DecisionTree) collectLeafRisks(leafRisks map[*DecisionTree]float64, N float64) error {
+	risk, err := t.leafRisk()
+	if err != nil {
+		return err
+	}
+	leafRisks[t] = risk / N
+
+	for _, child := range []*DecisionTree{t.left, t.right} {
+		if child != nil {
+			if err := child.collectLeafRisks(leafRisks, N); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the risk of the observations in this node if it were a leaf: the (weighted) number of misclassified observations
+// for classification trees, the (weighted) sum of squared errors for regression trees.
+func (t *DecisionTree) leafRisk() (risk float64, err error) {
+	prediction := t.Classification
+	if !t.IsLeaf() || prediction == nil {
+		if prediction, err = t.getLeafValue(); err != nil {
+			return 0.0, err
+		}
+	}
+	for _, obs := range t.Observations {
+		loss, err := t.loss(prediction, (*obs)[t.Options.TargetAttribute])
+		if err != nil {
+			return 0.0, err
+		}
+		risk += t.weightOf(obs) * loss
+	}
+	return
+}
+
+// Returns the loss of predicting the value for an observation with the given target: 0/1 loss (or the cost given
+// by Options.LossMatrix) for classification trees, squared error for regression trees.
+func (t *DecisionTree) loss(predicted Value, actual Value) (float64, error) {
+	if t.Options.LeafEstimator != nil {
+		p, err := _float(predicted)
+		if err != nil {
+			return 0.0, err
+		}
+		y, err := _float(actual)
+		return (p - y) * (p - y), err
+	}
+
+	if t.Options.LossMatrix != nil {
+		return t.Options.LossMatrix.cost(actual, predicted), nil
+	}
+	if isEq, err := _eq(predicted, actual); err != nil {
+		return 0.0, err
+	} else if isEq {
+		return 0.0, nil
+	}
+	return 1.0, nil
+}
+
+// Returns the total (weighted) loss of this tree on the given observations.
+func (t *DecisionTree) testError(observations []*Observation) (total float64, err error) {
+	for _, obs := range observations {
+		prediction, err := t.Classify(obs)
+		if err != nil {
+			return 0.0, err
+		}
+		loss, err := t.loss(prediction, (*obs)[t.Options.TargetAttribute])
+		if err != nil {
+			return 0.0, err
+		}
+		total += t.weightOf(obs) * loss
+	}
+	return
+}
+
+// Returns the risk and the number of leaves of the subtree rooted in this node (treating collapsed nodes as leaves), and its
+// internal (not yet collapsed) nodes whose collapse increases the risk the least per removed leaf, together with that
+// increase g(t) = (R(t) - R(T_t)) / (|leaves(T_t)| - 1). The subtree is visited once, bottom-up.
+func (t *DecisionTree) weakestLinks(leafRisks map[*DecisionTree]float64, collapsed map[*DecisionTree]bool) (risk float64, leaves int, weakest []*DecisionTree, minG float64) {
+	minG = math.Inf(1)
+
+	var visit func(node *DecisionTree) (float64, int)
+	visit = func(node *DecisionTree) (float64, int) {
+		if node.IsLeaf() || collapsed[node] {
+			return leafRisks[node], 1
+		}
+
+		subtreeRisk, subtreeLeaves := 0.0, 0
+		for _, child := range []*DecisionTree{node.left, node.right} {
+			if child != nil {
+				childRisk, childLeaves := visit(child)
+				subtreeRisk, subtreeLeaves = subtreeRisk+childRisk, subtreeLeaves+childLeaves
+			}
+		}
+
+		g := (leafRisks[node] - subtreeRisk) / float64(subtreeLeaves-1)
+		if g < minG-ALPHA_TOLERANCE {
+			weakest, minG = []*DecisionTree{node}, g
+		} else if g <= minG+ALPHA_TOLERANCE {
+			weakest = append(weakest, node)
+		}
+		return subtreeRisk, subtreeLeaves
+	}
+	risk, leaves = visit(t)
+	return
+}
+
+// Turns the collapsed nodes of the tree into leaves.
+func (t *DecisionTree) collapseNodes(collapsed map[*DecisionTree]bool) error {
+	if t.IsLeaf() {
+		return nil
+	}
+	if collapsed[t] {
+		return t.collapse()
+	}
+
+	for _, child := range []*DecisionTree{t.left, t.right} {
+		if child != nil {
+			if err := child.collapseNodes(collapsed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Removes the subtrees of this node, turning it into a leaf.
+func (t *DecisionTree) collapse() (err error) {
+	if t.Classification, err = t.getLeafValue(); err != nil {
+		return err
+	}
+	t.left, t.right = nil, nil
+	t.SplitPredictor, t.SplitValue = nil, nil
+	t.Surrogates, t.MissingGoesLeft = nil, false
+	return nil
+}
@@ -1,5 +1,10 @@
 package decision_tree
 
+import (
+	"math"
+	"sort"
+)
+
 // Blueprint interface for the purity measure calculation.
 type AbstractPurityMetric interface {
 	SplitPurity(predictor string, targetAttribute string, t *DecisionTree) (bestSplitIndex *int, purityAtSplit *float64, err error)
@@ -11,91 +16,272 @@ type AbstractPurityMetric interface {
 // Gini purity measure
 type GiniPurity struct{}
 
-// Helper function to calculate the gini impurity of a set of observations.
+// Helper function to calculate the gini impurity of a set of observations (with any number of distinct target classes).
 func (g GiniPurity) SlicePurity(data []*Observation, targetAttribute string) (slicePurity float64, err error) {
-	count, count1 := float64(len(data)), 0.0
-	if count == 0.0 {
-		return 0.0, nil
+	return accumulatedPurity(g.newAccumulator(), data, targetAttribute, unitWeight)
+}
+
+// Given the predictor and a tree node, this function returns the best split of observations according to Gini impurity measure.
+// Output: a tuple containing the best split index and the combined gini impurity measure of the split (average of impurities
+// of both regions of the split, weighted by their sizes)
+// Side effects: observations in the node are reoredered in a sorted fashion according to values of provided predictor.
+func (g GiniPurity) SplitPurity(predictor string, targetAttribute string, t *DecisionTree) (ptrBestSplitIndex *int, ptrPurityAtSplit *float64, err error) {
+	return scanSplits(g, predictor, targetAttribute, t)
+}
+
+func (g GiniPurity) newAccumulator() targetAccumulator {
+	return newClassCounts(gini)
+}
+
+func (g GiniPurity) newCostAccumulator(losses LossMatrix) targetAccumulator {
+	return newClassCounts(losses.gini)
+}
+
+func (g GiniPurity) splitPurity(left, right targetAccumulator) float64 {
+	return weightedSplitImpurity(left, right)
+}
+
+// Returns the gini impurity of the class distribution described by the counts.
+func gini(counts map[Value]float64, total float64) float64 {
+	sumSquares := 0.0
+	for _, count := range counts {
+		p := count / total
+		sumSquares += p * p
 	}
+	return 1 - sumSquares
+}
+
+// --------------------------------------------------------------------------------------------------
+
+// Entropy purity measure (information gain, as used by ID3)
+type EntropyPurity struct{}
+
+// Helper function to calculate the entropy (in bits) of a set of observations.
+func (e EntropyPurity) SlicePurity(data []*Observation, targetAttribute string) (slicePurity float64, err error) {
+	return accumulatedPurity(e.newAccumulator(), data, targetAttribute, unitWeight)
+}
+
+// Given the predictor and a tree node, this function returns the split of observations maximizing the information gain.
+// Output: a tuple containing the best split index and the weighted average of entropies of both regions of the split
+// Side effects: observations in the node are reordered in a sorted fashion according to values of provided predictor.
+func (e EntropyPurity) SplitPurity(predictor string, targetAttribute string, t *DecisionTree) (ptrBestSplitIndex *int, ptrPurityAtSplit *float64, err error) {
+	return scanSplits(e, predictor, targetAttribute, t)
+}
+
+func (e EntropyPurity) newAccumulator() targetAccumulator {
+	return newClassCounts(entropy)
+}
 
-	firstVal := (*data[0])[targetAttribute]
-	for _, obs := range data {
-		if isEq, err := _eq(firstVal, (*obs)[targetAttribute]); err != nil {
-			return 1.0, err
-		} else if isEq {
-			count1 += 1.0
+func (e EntropyPurity) splitPurity(left, right targetAccumulator) float64 {
+	return weightedSplitImpurity(left, right)
+}
+
+// Returns the entropy (in bits) of the class distribution described by the counts.
+func entropy(counts map[Value]float64, total float64) (h float64) {
+	for _, count := range counts {
+		if count > 0.0 {
+			p := count / total
+			h -= p * math.Log2(p)
 		}
 	}
+	return
+}
+
+// --------------------------------------------------------------------------------------------------
 
-	p1 := count1 / count
-	return 1 - p1*p1 - (1-p1)*(1-p1), nil
+// Gain ratio purity measure (information gain normalized by the split information, as used by C4.5)
+type GainRatioPurity struct{}
+
+// Helper function to calculate the entropy (in bits) of a set of observations.
+func (g GainRatioPurity) SlicePurity(data []*Observation, targetAttribute string) (slicePurity float64, err error) {
+	return accumulatedPurity(g.newAccumulator(), data, targetAttribute, unitWeight)
 }
 
-// Given the predictor and a tree node, this function returns the best split of observations according to Gini impurity measure.
-// Output: a tuple containing the best split index and the combined gini impurity measure of the split (sum of impurities of both regions of the split)
-// Side effects: observations in the node are reoredered in a sorted fashion according to values of provided predictor.
-func (g GiniPurity) SplitPurity(predictor string, targetAttribute string, t *DecisionTree) (ptrBestSplitIndex *int, ptrPurityAtSplit *float64, err error) {
-	t.sortByPredictor(predictor)
-	goods, error := g.calculateCummulativeGoodSlice(t.Observations, targetAttribute) // goods[i] <=> count of observations with target=1 in t.Observations[:i]
+// Given the predictor and a tree node, this function returns the split of observations maximizing the gain ratio.
+// Output: a tuple containing the best split index and the entropy of the node scaled by one minus the gain ratio
+// of the split. The gain ratio lies in [0, 1], so smaller values still denote better splits without leaving the impurity
+// scale: the purity lies between 0 (for a gain ratio of 1) and the entropy of the node.
+// Side effects: observations in the node are reordered in a sorted fashion according to values of provided predictor.
+func (g GainRatioPurity) SplitPurity(predictor string, targetAttribute string, t *DecisionTree) (ptrBestSplitIndex *int, ptrPurityAtSplit *float64, err error) {
+	return scanSplits(g, predictor, targetAttribute, t)
+}
+
+func (g GainRatioPurity) newAccumulator() targetAccumulator {
+	return newClassCounts(entropy)
+}
 
-	if error != nil {
-		return
+func (g GainRatioPurity) splitPurity(left, right targetAccumulator) float64 {
+	l, r := left.(*classCounts), right.(*classCounts)
+
+	parent := map[Value]float64{}
+	for class, count := range l.counts {
+		parent[class] += count
+	}
+	for class, count := range r.counts {
+		parent[class] += count
 	}
+	total := l.total + r.total
+	parentEntropy := entropy(parent, total)
+
+	splitInfo := entropy(map[Value]float64{0: l.total, 1: r.total}, total)
+	if splitInfo <= 0.0 {
+		return parentEntropy
+	}
+	gain := parentEntropy - weightedSplitImpurity(left, right)
+	return math.Max(parentEntropy*(1.0-gain/splitInfo), 0.0) // guards against rounding errors
+}
 
-	sumGood := (*goods)[len(*goods)-1]
-	countAll := len(*goods)
+// --------------------------------------------------------------------------------------------------
 
-	for splitIndex, goodCount := range *goods {
-		if splitIndex < len(t.Observations)-1 {
-			thisVal, nextVal := (*t.Observations[splitIndex])[predictor], (*t.Observations[splitIndex+1])[predictor]
-			if isEq, _ := _eq(thisVal, nextVal); isEq { // only care about ends of "runs"
-				continue
-			}
+// Mean squared error purity measure (variance reduction) for regression trees
+type MSEPurity struct{}
 
-		}
+// Helper function to calculate the variance of the (numeric) target values of a set of observations.
+func (m MSEPurity) SlicePurity(data []*Observation, targetAttribute string) (slicePurity float64, err error) {
+	return accumulatedPurity(m.newAccumulator(), data, targetAttribute, unitWeight)
+}
 
-		countL, goodL := float64(splitIndex), float64(goodCount)
-		countR, goodR := float64(countAll-splitIndex-1), float64(sumGood-goodCount)
+// Given the predictor and a tree node, this function returns the split of observations maximizing the variance reduction.
+// Output: a tuple containing the best split index and the weighted average of variances of both regions of the split
+// Side effects: observations in the node are reordered in a sorted fashion according to values of provided predictor.
+func (m MSEPurity) SplitPurity(predictor string, targetAttribute string, t *DecisionTree) (ptrBestSplitIndex *int, ptrPurityAtSplit *float64, err error) {
+	return scanSplits(m, predictor, targetAttribute, t)
+}
 
-		if int(countL) < t.Options.MinSplitSize || int(countR) < t.Options.MinSplitSize {
-			continue
-		}
+func (m MSEPurity) newAccumulator() targetAccumulator {
+	return &moments{}
+}
+
+func (m MSEPurity) splitPurity(left, right targetAccumulator) float64 {
+	return weightedSplitImpurity(left, right)
+}
+
+// --------------------------------------------------------------------------------------------------
+
+// Mean absolute error purity measure for regression trees (pairs naturally with the MedianEstimator)
+type MAEPurity struct{}
+
+// Helper function to calculate the mean absolute deviation of the (numeric) target values from their median.
+func (m MAEPurity) SlicePurity(data []*Observation, targetAttribute string) (slicePurity float64, err error) {
+	return m.weightedSlicePurity(data, targetAttribute, unitWeight)
+}
+
+// Helper function to calculate the weighted mean absolute deviation of the target values from their weighted median.
+func (m MAEPurity) weightedSlicePurity(data []*Observation, targetAttribute string, weightOf func(*Observation) float64) (float64, error) {
+	targets, err := numericTargets(data, targetAttribute)
+	if err != nil || len(data) == 0 {
+		return 0.0, err
+	}
+
+	tracker := newMedianTracker(targets)
+	for i, obs := range data {
+		tracker.push(targets[i], weightOf(obs))
+	}
+	if tracker.totalWeight <= 0.0 {
+		return 0.0, nil
+	}
+	return tracker.absDeviation() / tracker.totalWeight, nil
+}
+
+// Given the predictor and a tree node, this function returns the split of observations minimizing the absolute error.
+// Output: a tuple containing the best split index and the mean absolute deviation of the split (each region about its median)
+// Observations missing the predictor are ignored, the others count with their weights.
+func (m MAEPurity) SplitPurity(predictor string, targetAttribute string, t *DecisionTree) (ptrBestSplitIndex *int, ptrPurityAtSplit *float64, err error) {
+	t.sortByPredictor(predictor)
+	N := t.presentCount(predictor)
+	targets, err := numericTargets(t.Observations[:N], targetAttribute)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		giniL := 1 - (goodL/countL)*(goodL/countL) - ((countL-goodL)/countL)*((countL-goodL)/countL)
-		giniR := 1 - (goodR/countR)*(goodR/countR) - ((countR-goodR)/countR)*((countR-goodR)/countR)
-		gini := (countL*giniL + countR*giniR)
+	// prefix[i] and suffix[i] hold the absolute deviations (and weights) of Observations[:i] and Observations[i:] respectively
+	prefix, suffix := make([]float64, N+1), make([]float64, N+1)
+	prefixWeight, suffixWeight := make([]float64, N+1), make([]float64, N+1)
+	leftTracker, rightTracker := newMedianTracker(targets), newMedianTracker(targets)
+	for i := 0; i < N; i++ {
+		leftTracker.push(targets[i], t.weightOf(t.Observations[i]))
+		rightTracker.push(targets[N-i-1], t.weightOf(t.Observations[N-i-1]))
+		prefix[i+1], suffix[N-i-1] = leftTracker.absDeviation(), rightTracker.absDeviation()
+		prefixWeight[i+1], suffixWeight[N-i-1] = leftTracker.totalWeight, rightTracker.totalWeight
+	}
 
-		if ptrPurityAtSplit == nil || *ptrPurityAtSplit > gini {
-			ptrPurityAtSplit = &gini
+	for splitIndex := 1; splitIndex < N; splitIndex++ {
+		if !t.isEligibleSplit(predictor, splitIndex, prefixWeight[splitIndex], suffixWeight[splitIndex]) {
+			continue
+		}
+		purity := (prefix[splitIndex] + suffix[splitIndex]) / prefixWeight[N]
+		if ptrPurityAtSplit == nil || *ptrPurityAtSplit > purity {
 			i := splitIndex
-			ptrBestSplitIndex = &i
+			ptrPurityAtSplit, ptrBestSplitIndex = &purity, &i
 		}
 	}
+	return
+}
 
-	if ptrPurityAtSplit != nil {
-		x := (*ptrPurityAtSplit / float64(countAll))
-		ptrPurityAtSplit = &x
+// Returns the target values of the observations as float64s.
+func numericTargets(data []*Observation, targetAttribute string) ([]float64, error) {
+	targets := make([]float64, len(data))
+	for i, obs := range data {
+		y, err := _float((*obs)[targetAttribute])
+		if err != nil {
+			return nil, err
+		}
+		targets[i] = y
 	}
-	return
+	return targets, nil
+}
+
+// Keeps track of the weighted median of a growing set of values drawn from a known domain, together with the weights and
+// sums of the values below and above it (Fenwick trees indexed by the rank of the value within the domain), so that
+// the total (weighted) absolute deviation from the median is available at any time.
+type medianTracker struct {
+	domain                []float64 // sorted distinct values that may be pushed
+	weights, sums         []float64 // Fenwick trees of weights and weighted values (1-based)
+	totalWeight, totalSum float64
 }
 
-// Returns the cummulative count of observations with target = 1 up to the given index (result is an array of ints).
-func (g GiniPurity) calculateCummulativeGoodSlice(observations []*Observation, target string) (*[]int, error) {
-	if len(observations) == 0 {
-		return &[]int{}, nil
+func newMedianTracker(domain []float64) *medianTracker {
+	sorted := append([]float64{}, domain...)
+	sort.Float64s(sorted)
+	distinct := sorted[:0]
+	for i, y := range sorted {
+		if i == 0 || y != sorted[i-1] {
+			distinct = append(distinct, y)
+		}
 	}
+	return &medianTracker{distinct, make([]float64, len(distinct)+1), make([]float64, len(distinct)+1), 0.0, 0.0}
+}
 
-	firstVal := (*observations[0])[target]
-	goods := make([]int, len(observations)+1)
-	goods[0] = 0
+func (m *medianTracker) push(y float64, weight float64) {
+	for i := sort.SearchFloat64s(m.domain, y) + 1; i < len(m.weights); i += i & -i {
+		m.weights[i] += weight
+		m.sums[i] += weight * y
+	}
+	m.totalWeight += weight
+	m.totalSum += weight * y
+}
 
-	for i, obs := range observations {
-		goods[i+1] = goods[i]
-		if isEq, err := _eq(firstVal, (*obs)[target]); err != nil {
-			return nil, err
-		} else if isEq {
-			goods[i+1]++
+func (m *medianTracker) absDeviation() float64 {
+	if m.totalWeight <= 0.0 {
+		return 0.0
+	}
+
+	// descend the Fenwick tree to the smallest rank whose cumulative weight reaches half of the total weight
+	rank, weightBelow, sumBelow := 0, 0.0, 0.0
+	step := 1
+	for step*2 < len(m.weights) {
+		step *= 2
+	}
+	for ; step > 0; step /= 2 {
+		if next := rank + step; next < len(m.weights) && weightBelow+m.weights[next] < m.totalWeight/2 {
+			rank, weightBelow, sumBelow = next, weightBelow+m.weights[next], sumBelow+m.sums[next]
 		}
 	}
-	return &goods, nil
+	if rank >= len(m.domain) {
+		rank = len(m.domain) - 1
+	}
+
+	// values below the median contribute (median - y), the others (y - median)
+	median := m.domain[rank]
+	return (median*weightBelow - sumBelow) + (m.totalSum - sumBelow - median*(m.totalWeight-weightBelow))
 }
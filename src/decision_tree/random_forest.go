@@ -0,0 +1,175 @@
+package decision_tree
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// An ensemble of decision trees, each grown on a bootstrap sample of the observations with a random subset of predictors
+// considered in every node. Classification forests vote by averaging the class probabilities of the trees,
+// regression forests (Options.LeafEstimator set) average the predictions of the trees.
+type RandomForest struct {
+	Trees    []*DecisionTree
+	NumTrees int      // Number of trees to grow
+	Seed     int64    // Seed of the random generator driving the bootstrap samples and the predictor subsets
+	Options  *Options // Grow options of the trees; MaxFeatures defaults to sqrt(#predictors) (#predictors/3 for regression)
+
+	observations []*Observation // Training observations, kept for the out-of-bag error estimate
+	outOfBag     [][]int        // Indices of the observations left out of the bootstrap sample of each tree
+}
+
+// Grows the forest on the given observations.
+func (f *RandomForest) Grow(observations []*Observation) error {
+	if f.NumTrees <= 0 {
+		return errors.New("Number of trees must be positive.")
+	}
+	if len(observations) == 0 {
+		return errors.New("Cannot grow a forest without observations.")
+	}
+	if err := checkWeights(observations, f.Options); err != nil { // the out-of-bag observations count with their weights too
+		return err
+	}
+
+	random := rand.New(rand.NewSource(f.Seed))
+	f.Trees, f.outOfBag, f.observations = make([]*DecisionTree, f.NumTrees), make([][]int, f.NumTrees), observations
+
+	for i := range f.Trees {
+		options := *f.Options
+		options.Seed = random.Int63()
+		if options.MaxFeatures <= 0 {
+			options.MaxFeatures = f.defaultMaxFeatures()
+		}
+
+		sample, inBag := make([]*Observation, len(observations)), make([]bool, len(observations))
+		for j := range sample {
+			k := random.Intn(len(observations))
+			sample[j], inBag[k] = observations[k], true
+		}
+		for j := range observations {
+			if !inBag[j] {
+				f.outOfBag[i] = append(f.outOfBag[i], j)
+			}
+		}
+
+		tree := new(DecisionTree)
+		if err := tree.InitRoot(&options, sample); err != nil {
+			return err
+		}
+		if err := tree.Expand(true); err != nil {
+			return err
+		}
+		f.Trees[i] = tree
+	}
+	return nil
+}
+
+func (f *RandomForest) defaultMaxFeatures() int {
+	p := float64(len(*f.Options.Predictors))
+	if f.isRegression() {
+		return int(math.Max(1, math.Floor(p/3)))
+	}
+	return int(math.Max(1, math.Floor(math.Sqrt(p))))
+}
+
+func (f *RandomForest) isRegression() bool {
+	return f.Options.LeafEstimator != nil
+}
+
+// Returns the classification for a new observation o: the class predicted by most trees (ties resolved in favour of
+// the smaller class), every tree honouring the class weights and the misclassification costs of the grow options;
+// or the average prediction for regression forests.
+func (f *RandomForest) Classify(o *Observation) (Value, error) {
+	return f.classifyWith(f.Trees, o)
+}
+
+// Returns the class probabilities for a new observation o, averaged over all trees of the forest. The probabilities
+// are estimated from the class counts of the leaves, so unlike Classify they do not reflect class weights nor costs.
+func (f *RandomForest) ClassifyProba(o *Observation) (map[Value]float64, error) {
+	return f.probabilitiesWith(f.Trees, o)
+}
+
+// Returns the out-of-bag error estimate of the forest: every training observation is classified by the trees that did not
+// see it during training. The error is the (weighted) misclassification rate (mean squared error for regression forests)
+// over the observations left out of at least one bootstrap sample.
+func (f *RandomForest) OOBError() (float64, error) {
+	if len(f.Trees) == 0 {
+		return 0.0, errors.New("The forest has not been grown yet.")
+	}
+
+	voters := make([][]*DecisionTree, len(f.observations))
+	for i, indices := range f.outOfBag {
+		for _, j := range indices {
+			voters[j] = append(voters[j], f.Trees[i])
+		}
+	}
+
+	total, count := 0.0, 0.0
+	for j, trees := range voters {
+		if len(trees) == 0 {
+			continue
+		}
+		prediction, err := f.classifyWith(trees, f.observations[j])
+		if err != nil {
+			return 0.0, err
+		}
+		loss, err := trees[0].loss(prediction, (*f.observations[j])[f.Options.TargetAttribute])
+		if err != nil {
+			return 0.0, err
+		}
+		weight := trees[0].weightOf(f.observations[j])
+		total, count = total+weight*loss, count+weight
+	}
+
+	if count == 0 {
+		return 0.0, errors.New("No observation was left out of bag.")
+	}
+	return total / count, nil
+}
+
+func (f *RandomForest) classifyWith(trees []*DecisionTree, o *Observation) (Value, error) {
+	if f.isRegression() {
+		sum := 0.0
+		for _, tree := range trees {
+			prediction, err := tree.Predict(o)
+			if err != nil {
+				return nil, err
+			}
+			sum += prediction
+		}
+		return sum / float64(len(trees)), nil
+	}
+
+	if len(trees) == 0 {
+		return nil, errors.New("The forest has not been grown yet.")
+	}
+	votes := newClassCounts(gini)
+	for _, tree := range trees {
+		prediction, err := tree.Classify(o)
+		if err != nil {
+			return nil, err
+		}
+		if err := votes.add(prediction, 1.0); err != nil {
+			return nil, err
+		}
+	}
+	return votes.majority(), nil
+}
+
+func (f *RandomForest) probabilitiesWith(trees []*DecisionTree, o *Observation) (map[Value]float64, error) {
+	if len(trees) == 0 {
+		return nil, errors.New("The forest has not been grown yet.")
+	}
+
+	probabilities := map[Value]float64{}
+	for _, tree := range trees {
+		treeProbabilities, err := tree.ClassifyProba(o)
+		if err != nil {
+			return nil, err
+		}
+		for class, p := range treeProbabilities {
+			probabilities[class] += p / float64(len(trees))
+		}
+	}
+	return probabilities, nil
+}
@@ -15,6 +15,35 @@ func (s ByPredictorValueFloat) Swap(i, j int) {
 
 func (s ByPredictorValueFloat) Less(i, j int) bool {
 	obsi, obsj := (*(*s.Observations)[i]), (*(*s.Observations)[j])
+	if isMissing(obsi[s.Predictor]) || isMissing(obsj[s.Predictor]) { // missing values come last
+		return !isMissing(obsi[s.Predictor])
+	}
 	isLess, _ := _lt(obsi[s.Predictor], obsj[s.Predictor])
 	return isLess
 }
+
+// Sorts observations by the rank of their (categorical) predictor value; equally ranked categories are kept in contiguous runs.
+type ByPredictorRank struct {
+	Predictor    string
+	Ranks        map[Value]float64
+	Observations *([]*Observation)
+}
+
+func (s ByPredictorRank) Len() int {
+	return len(*s.Observations)
+}
+
+func (s ByPredictorRank) Swap(i, j int) {
+	(*s.Observations)[i], (*s.Observations)[j] = (*s.Observations)[j], (*s.Observations)[i]
+}
+
+func (s ByPredictorRank) Less(i, j int) bool {
+	vi, vj := (*(*s.Observations)[i])[s.Predictor], (*(*s.Observations)[j])[s.Predictor]
+	if isMissing(vi) || isMissing(vj) { // missing values come last
+		return !isMissing(vi)
+	}
+	if ri, rj := s.Ranks[vi], s.Ranks[vj]; ri != rj {
+		return ri < rj
+	}
+	return _before(vi, vj)
+}
@@ -0,0 +1,269 @@
+package decision_tree
+
+import (
+	"errors"
+	"math"
+)
+
+// Implemented by purity metrics which cannot be evaluated by a targetAccumulator, but still honour observation weights.
+type weightedPurityMetric interface {
+	weightedSlicePurity(data []*Observation, targetAttribute string, weightOf func(*Observation) float64) (float64, error)
+}
+
+// Collects statistics about the target values of a set of observations, so that the impurity of the set
+// can be updated incrementally as observations move from one side of a split to the other.
+type targetAccumulator interface {
+	add(target Value, weight float64) error
+	remove(target Value, weight float64) error
+	impurity() float64
+	weight() float64
+	merge(other targetAccumulator, sign float64) // adds (sign 1) or removes (sign -1) all statistics of another accumulator
+}
+
+// Implemented by purity metrics whose best split can be found by a single scan over the sorted observations.
+type scanningPurityMetric interface {
+	newAccumulator() targetAccumulator
+	splitPurity(left, right targetAccumulator) float64
+}
+
+// Per-class counts of target values; the impurity of the counts is evaluated by the provided function.
+type classCounts struct {
+	counts     map[Value]float64
+	total      float64
+	impurityFn func(counts map[Value]float64, total float64) float64
+}
+
+func newClassCounts(impurityFn func(counts map[Value]float64, total float64) float64) *classCounts {
+	return &classCounts{map[Value]float64{}, 0.0, impurityFn}
+}
+
+func (c *classCounts) add(target Value, weight float64) error {
+	if err := _check(target); err != nil {
+		return err
+	}
+	c.counts[target] += weight
+	c.total += weight
+	return nil
+}
+
+func (c *classCounts) remove(target Value, weight float64) error {
+	if err := _check(target); err != nil {
+		return err
+	}
+	c.counts[target] -= weight
+	c.total -= weight
+	return nil
+}
+
+func (c *classCounts) impurity() float64 {
+	if c.total <= 0.0 {
+		return 0.0
+	}
+	return c.impurityFn(c.counts, c.total)
+}
+
+func (c *classCounts) weight() float64 {
+	return c.total
+}
+
+func (c *classCounts) merge(other targetAccumulator, sign float64) {
+	o := other.(*classCounts)
+	for class, count := range o.counts {
+		c.counts[class] += sign * count
+	}
+	c.total += sign * o.total
+}
+
+// Returns the class with the highest count; ties are resolved in favour of the smaller class.
+func (c *classCounts) majority() (best Value) {
+	bestCount := 0.0
+	for class, count := range c.counts {
+		if best == nil || count > bestCount || (count == bestCount && _before(class, best)) {
+			best, bestCount = class, count
+		}
+	}
+	return
+}
+
+// Running moments (count, sum and sum of squares) of numeric target values; the impurity is their variance.
+type moments struct {
+	w, sum, sumSq float64
+}
+
+func (m *moments) add(target Value, weight float64) error {
+	y, err := _float(target)
+	if err != nil {
+		return err
+	}
+	m.w, m.sum, m.sumSq = m.w+weight, m.sum+weight*y, m.sumSq+weight*y*y
+	return nil
+}
+
+func (m *moments) remove(target Value, weight float64) error {
+	return m.add(target, -weight)
+}
+
+func (m *moments) impurity() float64 {
+	if m.w <= 0.0 {
+		return 0.0
+	}
+	mean := m.sum / m.w
+	return math.Max(m.sumSq/m.w-mean*mean, 0.0)
+}
+
+func (m *moments) weight() float64 {
+	return m.w
+}
+
+func (m *moments) merge(other targetAccumulator, sign float64) {
+	o := other.(*moments)
+	m.w, m.sum, m.sumSq = m.w+sign*o.w, m.sum+sign*o.sum, m.sumSq+sign*o.sumSq
+}
+
+// Returns the impurity of a set of observations as measured by the given (empty) accumulator,
+// each observation counting with the weight given by weightOf.
+func accumulatedPurity(acc targetAccumulator, data []*Observation, targetAttribute string, weightOf func(*Observation) float64) (float64, error) {
+	for _, obs := range data {
+		if err := acc.add((*obs)[targetAttribute], weightOf(obs)); err != nil {
+			return 0.0, err
+		}
+	}
+	return acc.impurity(), nil
+}
+
+// Finds the best split of the observations in node t on the given predictor. The observations are sorted by the predictor
+// and scanned once, evaluating the split purity at the end of every run of equal predictor values. Splits leaving less
+// than MinSplitSize or MinLeafSize (total sample weight of) observations on either side are not considered. Observations missing the predictor
+// are ignored, the others count with their weights (see weightOf).
+// Output: index i of the best split (left region is Observations[:i], right region is Observations[i:]) and its purity.
+// Side effects: observations in the node are reordered in a sorted fashion according to values of provided predictor.
+func scanSplits(m scanningPurityMetric, predictor string, targetAttribute string, t *DecisionTree) (ptrBestSplitIndex *int, ptrPurityAtSplit *float64, err error) {
+	t.sortByPredictor(predictor)
+	observations := t.Observations[:t.presentCount(predictor)]
+	left, right := t.newAccumulator(m), t.newAccumulator(m)
+	leftSize, rightSize := 0.0, t.sampleSize(observations)
+
+	for _, obs := range observations {
+		if err = right.add((*obs)[targetAttribute], t.weightOf(obs)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for splitIndex := 1; splitIndex < len(observations); splitIndex++ {
+		moved, weight := (*observations[splitIndex-1])[targetAttribute], t.weightOf(observations[splitIndex-1])
+		left.add(moved, weight)
+		right.remove(moved, weight)
+		size := t.sampleWeight(observations[splitIndex-1])
+		leftSize, rightSize = leftSize+size, rightSize-size
+
+		if !t.isEligibleSplit(predictor, splitIndex, leftSize, rightSize) {
+			continue
+		}
+
+		purity := m.splitPurity(left, right)
+		if ptrPurityAtSplit == nil || *ptrPurityAtSplit > purity {
+			i := splitIndex
+			ptrPurityAtSplit, ptrBestSplitIndex = &purity, &i
+		}
+	}
+	return
+}
+
+// Returns true iff splitting the node (sorted by the predictor) at the given index falls on the end of a run of equal
+// predictor values and leaves at least MinSplitSize (and MinLeafSize) on both sides of the split, as measured by the total
+// sample weights of the present observations on the left and on the right.
+func (t *DecisionTree) isEligibleSplit(predictor string, splitIndex int, leftSize float64, rightSize float64) bool {
+	prevVal, thisVal := (*t.Observations[splitIndex-1])[predictor], (*t.Observations[splitIndex])[predictor]
+	if isEq, _ := _eq(prevVal, thisVal); isEq { // only care about ends of "runs"
+		return false
+	}
+	return t.isLargeEnough(leftSize, rightSize)
+}
+
+// Returns true iff both regions of a split, of the sizes (total sample weights) leftSize and rightSize, satisfy MinSplitSize
+// and MinLeafSize.
+func (t *DecisionTree) isLargeEnough(leftSize float64, rightSize float64) bool {
+	minSize := math.Max(float64(t.Options.MinSplitSize), float64(t.Options.MinLeafSize))
+	return leftSize >= minSize && rightSize >= minSize
+}
+
+// Returns the sample weight of the observation, as given by its WeightAttribute value (or the internal weights of the grow
+// options); observations of trees grown without a WeightAttribute have unit weight. The weight values are expected
+// to be checked by checkWeights. Sample weights measure the sizes of nodes (see MinSplitSize) and their ClassCounts.
+func (t *DecisionTree) sampleWeight(obs *Observation) float64 {
+	if t.Options.sampleWeights != nil {
+		return t.Options.sampleWeights[obs]
+	} else if t.Options.WeightAttribute != "" {
+		if w, err := _float((*obs)[t.Options.WeightAttribute]); err == nil {
+			return w
+		}
+	}
+	return 1.0
+}
+
+// Returns the weight of the observation in the impurity and the leaf value of a node: its sample weight multiplied
+// by the weight of its target class (see Options.ClassWeights).
+func (t *DecisionTree) weightOf(obs *Observation) float64 {
+	weight := t.sampleWeight(obs)
+	if t.grow != nil && len(t.grow.classWeights) > 0 {
+		if target := (*obs)[t.Options.TargetAttribute]; _check(target) == nil { // invalid targets are reported elsewhere
+			if classWeight, ok := t.grow.classWeights[target]; ok {
+				weight *= classWeight
+			}
+		}
+	}
+	return weight
+}
+
+// Checks that every observation has a numeric, non-negative value of the weight attribute of the grow options (if any).
+func checkWeights(observations []*Observation, options *Options) error {
+	if options.WeightAttribute == "" || options.sampleWeights != nil {
+		return nil
+	}
+	for _, obs := range observations {
+		value := (*obs)[options.WeightAttribute]
+		if isMissing(value) {
+			return errors.New("Missing weight of an observation.")
+		}
+		if weight, err := _float(value); err != nil {
+			return errors.New("Weights must be numeric.")
+		} else if weight < 0.0 || math.IsNaN(weight) {
+			return errors.New("Weights must not be negative.")
+		}
+	}
+	return nil
+}
+
+// Returns true iff the observations of this tree do not all weigh the same (see weightOf).
+func (t *DecisionTree) isWeighted() bool {
+	return t.Options.WeightAttribute != "" || t.Options.sampleWeights != nil || (t.grow != nil && len(t.grow.classWeights) > 0)
+}
+
+// Returns the total weight of the observations.
+func (t *DecisionTree) totalWeight(observations []*Observation) (total float64) {
+	for _, obs := range observations {
+		total += t.weightOf(obs)
+	}
+	return
+}
+
+// Returns the size of the observations: their total sample weight.
+func (t *DecisionTree) sampleSize(observations []*Observation) (total float64) {
+	for _, obs := range observations {
+		total += t.sampleWeight(obs)
+	}
+	return
+}
+
+func unitWeight(obs *Observation) float64 {
+	return 1.0
+}
+
+// Returns the weighted average of impurities of both regions of a split.
+func weightedSplitImpurity(left, right targetAccumulator) float64 {
+	wL, wR := left.weight(), right.weight()
+	if wL+wR <= 0.0 {
+		return 0.0
+	}
+	return (wL*left.impurity() + wR*right.impurity()) / (wL + wR)
+}
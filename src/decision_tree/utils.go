@@ -1,9 +1,11 @@
 package decision_tree
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -90,20 +92,14 @@ func (t *DecisionTree) PrintTree(depth int, maxDepth int, verbose bool) string {
 				fmt.Printf("%s Classification=%s [%d observations]\n\n", strings.Repeat("--|", depth+1), classif, len(t.Observations))
 			}
 		} else {
-			splitPredictor := NO_PREDICTOR
-			var splitVal interface{}
-			splitVal, _ = _str(t.SplitValue)
-			splitPredictor = *t.SplitPredictor
 			if verbose {
-				fmt.Printf("%s (rule: %s < %s)[%d observations:[%s]]\n\n", strings.Repeat("--|", depth+1),
-					splitPredictor,
-					splitVal,
+				fmt.Printf("%s (rule: %s)[%d observations:[%s]]\n\n", strings.Repeat("--|", depth+1),
+					t.ruleString(),
 					len(t.Observations),
 					SerializeObservations(t.Observations, t.Options.TargetAttribute))
 			} else {
-				fmt.Printf("%s (rule: %s < %s)\n\n", strings.Repeat("--|", depth+1),
-					splitPredictor,
-					splitVal)
+				fmt.Printf("%s (rule: %s)\n\n", strings.Repeat("--|", depth+1),
+					t.ruleString())
 			}
 			return t.left.PrintTree(depth+1, maxDepth, verbose) + t.right.PrintTree(depth+1, maxDepth, verbose)
 		}
@@ -111,6 +107,15 @@ func (t *DecisionTree) PrintTree(depth int, maxDepth int, verbose bool) string {
 	return ""
 }
 
+// Returns the split rule of an internal node in a human readable form, e.g. "x < 4.200000" or "country in {CZ,SK}".
+func (t *DecisionTree) ruleString() string {
+	if categories, ok := t.SplitValue.(CategorySet); ok {
+		return *t.SplitPredictor + " in " + categories.String()
+	}
+	splitVal, _ := _str(t.SplitValue)
+	return *t.SplitPredictor + " < " + splitVal
+}
+
 // === Serialization
 
 func SerializeObservations(observations []*Observation, targetKey string) (out string) {
@@ -125,15 +130,55 @@ func SerializeObservations(observations []*Observation, targetKey string) (out s
 }
 
 type serializedTree struct {
-	SplitOn        *string         `json:"splitOn,omitempty"`
-	SplitValue     Value           `json:"splitValue,omitempty"`
-	IfLeq          *serializedTree `json:"ifLeq,omitempty"`
-	IfGt           *serializedTree `json:"ifGt,omitempty"`
-	Classification Value           `json:"classification,omitempty"`
+	TargetAttribute       string   `json:"target,omitempty"`
+	Predictors            []string `json:"predictors,omitempty"`
+	CategoricalPredictors []string `json:"categoricalPredictors,omitempty"`
+
+	SplitOn *string `json:"splitOn,omitempty"`
+	serializedRule
+	Surrogates      []*serializedSurrogate `json:"surrogates,omitempty"`
+	MissingGoesLeft bool                   `json:"missingGoesLeft,omitempty"`
+
+	IfLeq              *serializedTree `json:"ifLeq,omitempty"`
+	IfGt               *serializedTree `json:"ifGt,omitempty"`
+	Classification     Value           `json:"classification,omitempty"`
+	ClassificationType string          `json:"classificationType,omitempty"`
+
+	Distribution []*serializedClassCount `json:"distribution,omitempty"`
+}
+
+type serializedClassCount struct {
+	Class     Value   `json:"class"`
+	ClassType string  `json:"classType"`
+	Count     float64 `json:"count"`
+}
+
+// Split value of a (surrogate) split, either a threshold or a category set, along with the Go types of the values.
+type serializedRule struct {
+	SplitValue     Value    `json:"splitValue,omitempty"`
+	SplitValueType string   `json:"splitValueType,omitempty"`
+	SplitIn        []Value  `json:"splitIn,omitempty"`
+	SplitInTypes   []string `json:"splitInTypes,omitempty"`
+}
+
+type serializedSurrogate struct {
+	Predictor string `json:"predictor"`
+	serializedRule
+	Inverted  bool    `json:"inverted,omitempty"`
+	Agreement float64 `json:"agreement"`
 }
 
+// Returns the model (tree) as JSON. The root node additionally describes the target attribute and the predictors,
+// so that the model can be loaded back by LoadSerializedModel.
 func (t *DecisionTree) GetSerializedModel() string {
 	st := t.constructSerializedModel()
+	st.TargetAttribute = t.Options.TargetAttribute
+	if t.Options.Predictors != nil {
+		st.Predictors = *t.Options.Predictors
+	}
+	if t.Options.CategoricalPredictors != nil {
+		st.CategoricalPredictors = *t.Options.CategoricalPredictors
+	}
 	b, _ := json.Marshal(st)
 	return string(b)
 }
@@ -142,11 +187,17 @@ func (t *DecisionTree) constructSerializedModel() *serializedTree {
 	m := &serializedTree{}
 
 	if t.IsLeaf() {
-		m.Classification = t.Classification
+		m.Classification, m.ClassificationType = t.Classification, _type(t.Classification)
 	} else {
 		m.SplitOn = t.SplitPredictor
-		v := t.SplitValue.(float64)
-		m.SplitValue = &v
+		m.serializedRule = newSerializedRule(t.SplitValue)
+		for _, s := range t.Surrogates {
+			m.Surrogates = append(m.Surrogates, &serializedSurrogate{s.Predictor, newSerializedRule(s.SplitValue), s.Inverted, s.Agreement})
+		}
+		m.MissingGoesLeft = t.MissingGoesLeft
+	}
+	for _, class := range sortedClasses(t.ClassCounts) {
+		m.Distribution = append(m.Distribution, &serializedClassCount{class, _type(class), t.ClassCounts[class]})
 	}
 	if t.left != nil {
 		m.IfLeq = t.left.constructSerializedModel()
@@ -157,6 +208,157 @@ func (t *DecisionTree) constructSerializedModel() *serializedTree {
 	return m
 }
 
+func newSerializedRule(splitValue Value) (r serializedRule) {
+	if categories, ok := splitValue.(CategorySet); ok {
+		r.SplitIn = categories
+		for _, category := range categories {
+			r.SplitInTypes = append(r.SplitInTypes, _type(category))
+		}
+	} else {
+		r.SplitValue, r.SplitValueType = splitValue, _type(splitValue)
+	}
+	return
+}
+
+// Reconstructs a classify-ready tree from the JSON produced by GetSerializedModel. The types of split values and
+// classifications are preserved; models serialized without type information load numbers as float64.
+// Options of the loaded tree only describe the target attribute and the predictors, as the grow settings are not serialized.
+func LoadSerializedModel(data []byte) (*DecisionTree, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	st := &serializedTree{}
+	if err := decoder.Decode(st); err != nil {
+		return nil, err
+	}
+
+	t := new(DecisionTree)
+	t.initNode(&Options{TargetAttribute: st.TargetAttribute}, 0)
+	if err := t.loadSerializedModel(st); err != nil {
+		return nil, err
+	}
+
+	predictors, categorical := st.Predictors, st.CategoricalPredictors
+	if predictors == nil {
+		predictors = t.GetUsedPredictors()
+		sort.Strings(predictors)
+	}
+	if categorical == nil {
+		categorical = t.getCategoricalSplitPredictors()
+	}
+	t.Options.Predictors, t.Options.CategoricalPredictors = &predictors, &categorical
+	return t, nil
+}
+
+func (t *DecisionTree) loadSerializedModel(st *serializedTree) (err error) {
+	if st.Distribution != nil {
+		t.ClassCounts = map[Value]float64{}
+		for _, c := range st.Distribution {
+			class, err := decodeValue(c.Class, c.ClassType)
+			if err != nil {
+				return err
+			}
+			t.ClassCounts[class] = c.Count
+		}
+	}
+
+	if st.SplitOn == nil {
+		t.Classification, err = decodeValue(st.Classification, st.ClassificationType)
+		return err
+	}
+
+	if st.IfLeq == nil || st.IfGt == nil {
+		return errors.New("Invalid model: internal node is missing a subtree.")
+	}
+	predictor := *st.SplitOn
+	t.SplitPredictor = &predictor
+	if t.SplitValue, err = st.serializedRule.decode(); err != nil {
+		return err
+	}
+	for _, s := range st.Surrogates {
+		splitValue, err := s.serializedRule.decode()
+		if err != nil {
+			return err
+		}
+		t.Surrogates = append(t.Surrogates, &SurrogateSplit{s.Predictor, splitValue, s.Inverted, s.Agreement})
+	}
+	t.MissingGoesLeft = st.MissingGoesLeft
+
+	t.setLeft(new(DecisionTree))
+	t.setRight(new(DecisionTree))
+	if err = t.left.loadSerializedModel(st.IfLeq); err != nil {
+		return err
+	}
+	return t.right.loadSerializedModel(st.IfGt)
+}
+
+func (r serializedRule) decode() (Value, error) {
+	if r.SplitIn == nil {
+		return decodeValue(r.SplitValue, r.SplitValueType)
+	}
+
+	categories := CategorySet{}
+	for i, category := range r.SplitIn {
+		typ := ""
+		if i < len(r.SplitInTypes) {
+			typ = r.SplitInTypes[i]
+		}
+		value, err := decodeValue(category, typ)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, value)
+	}
+	return categories, nil
+}
+
+// Returns the classes of the distribution in ascending order.
+func sortedClasses(counts map[Value]float64) []Value {
+	classes := make([]Value, 0, len(counts))
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool { return _before(classes[i], classes[j]) })
+	return classes
+}
+
+// Returns the predictors split upon by category sets in the given tree.
+func (t *DecisionTree) getCategoricalSplitPredictors() []string {
+	found := map[string]bool{}
+	var visit func(node *DecisionTree)
+	visit = func(node *DecisionTree) {
+		if node == nil || node.IsLeaf() {
+			return
+		}
+		if _, ok := node.SplitValue.(CategorySet); ok {
+			found[*node.SplitPredictor] = true
+		}
+		for _, s := range node.Surrogates {
+			if _, ok := s.SplitValue.(CategorySet); ok {
+				found[s.Predictor] = true
+			}
+		}
+		visit(node.left)
+		visit(node.right)
+	}
+	visit(t)
+
+	predictors := []string{}
+	for predictor := range found {
+		predictors = append(predictors, predictor)
+	}
+	sort.Strings(predictors)
+	return predictors
+}
+
+// Derives the seed of a child node from the seed of its parent and the branch (SplitMix64 mixing).
+func deriveSeed(seed int64, branch uint64) int64 {
+	z := uint64(seed) + branch*0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return int64(z ^ (z >> 31))
+}
+
 // ====== operator wrappers ======
 
 func _lt(l interface{}, r interface{}) (retVal bool, err error) {
@@ -250,3 +452,61 @@ func _str(v interface{}) (str string, err error) {
 	}
 	return "", errors.New("Uncomparable or unsupported types.")
 }
+
+func _type(v interface{}) string {
+	switch v.(type) {
+	case float32, float64, int, string:
+		return fmt.Sprintf("%T", v)
+	}
+	return ""
+}
+
+// Converts a value decoded from JSON (with numbers kept as json.Number) to the given Go type.
+func decodeValue(v interface{}, typ string) (Value, error) {
+	number, isNumber := v.(json.Number)
+	switch {
+	case v == nil:
+		return nil, nil
+	case !isNumber:
+		if str, ok := v.(string); ok && (typ == "" || typ == "string") {
+			return str, nil
+		}
+	case typ == "int":
+		i, err := number.Int64()
+		return int(i), err
+	case typ == "float32":
+		f, err := strconv.ParseFloat(number.String(), 32)
+		return float32(f), err
+	case typ == "" || typ == "float64":
+		return number.Float64()
+	}
+	return nil, errors.New("Uncomparable or unsupported types.")
+}
+
+func _check(v interface{}) error {
+	switch v.(type) {
+	case float32, float64, int, string:
+		return nil
+	}
+	return errors.New("Uncomparable or unsupported types.")
+}
+
+// Total ordering over supported values: values of the same type are compared naturally, values of different types by type name.
+func _before(l interface{}, r interface{}) bool {
+	if isLess, err := _lt(l, r); err == nil {
+		return isLess
+	}
+	return fmt.Sprintf("%T", l) < fmt.Sprintf("%T", r)
+}
+
+func _float(v interface{}) (float64, error) {
+	switch vv := v.(type) {
+	case float32:
+		return float64(vv), nil
+	case float64:
+		return vv, nil
+	case int:
+		return float64(vv), nil
+	}
+	return 0.0, errors.New("Non-numeric or unsupported types.")
+}